@@ -2,16 +2,20 @@ package app
 
 import (
 	"context"
+	nethttp "net/http"
 
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/config"
 	httptransport "github.com/iamsorryprincess/wildberries-bot/cmd/api/http"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/repository"
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/service"
 	telegramtransport "github.com/iamsorryprincess/wildberries-bot/cmd/api/telegram"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/background"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/http/middleware"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/queue/memory"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/telegram"
 )
 
@@ -32,14 +36,31 @@ type App struct {
 	productRepository  *repository.MysqlProductRepository
 	sizeRepository     *repository.MysqlSizeRepository
 	trackingRepository *repository.MysqlTrackingRepository
+	chatRepository     *repository.MysqlChatRepository
+	statsRepository    *repository.MysqlStatsRepository
+	stockRepository    *repository.MysqlStockRepository
+	detailsRepository  *repository.MysqlProductDetailsRepository
+	regionRepository   *repository.MysqlRegionRepository
+	brandRepository    *repository.MysqlBrandRepository
+	treeRepository     *repository.MysqlCategoryTreeRepository
 
 	productClient *httptransport.ProductClient
+	catalogClient *httptransport.CatalogClient
+	ozonClient    *httptransport.OzonClient
+	marketplaces  *service.MarketplaceRegistry
+	cardClient    *httptransport.CardClient
 	botClient     *telegram.BotClient
 
 	sender *telegramtransport.Sender
 
-	trackingService *service.TrackingService
-	productService  *service.ProductService
+	httpServer *http.Server
+
+	trackingService   *service.TrackingService
+	productService    *service.ProductService
+	categoryService   *service.CategoryService
+	enrichmentService *service.EnrichmentService
+
+	enrichmentQueue *memory.Queue[model.ProductRef]
 
 	worker *background.Worker
 }
@@ -76,7 +97,13 @@ func (a *App) Run() {
 		return
 	}
 
-	a.initWorkers()
+	if err = a.initWorkers(); err != nil {
+		return
+	}
+
+	if err = a.startTelegram(); err != nil {
+		return
+	}
 
 	a.logger.Info().Msg("service started")
 
@@ -109,12 +136,21 @@ func (a *App) initRepositories() {
 	a.productRepository = repository.NewMysqlProductRepository(a.logger, a.mysqlConn)
 	a.sizeRepository = repository.NewMysqlSizeRepository(a.mysqlConn)
 	a.trackingRepository = repository.NewMysqlTrackingRepository(a.logger, a.mysqlConn)
+	a.chatRepository = repository.NewMysqlChatRepository(a.mysqlConn)
+	a.statsRepository = repository.NewMysqlStatsRepository(a.mysqlConn)
+	a.stockRepository = repository.NewMysqlStockRepository(a.mysqlConn)
+	a.detailsRepository = repository.NewMysqlProductDetailsRepository(a.mysqlConn)
+	a.regionRepository = repository.NewMysqlRegionRepository(a.mysqlConn)
+	a.brandRepository = repository.NewMysqlBrandRepository(a.mysqlConn)
+	a.treeRepository = repository.NewMysqlCategoryTreeRepository(a.mysqlConn)
 }
 
 func (a *App) initTelegram() error {
-	defaultHandlerOption := telegramtransport.NewStartHandlerOption(a.logger, a.trackingRepository)
-
-	botClient, err := telegram.NewBotClient(a.config.TelegramConfig, defaultHandlerOption)
+	botClient, err := telegram.NewBotClient(
+		a.config.TelegramConfig,
+		telegramtransport.NewStartHandlerOption(a.logger, a.trackingRepository, a.chatRepository),
+		telegramtransport.NewChatMiddlewareOption(a.logger, a.chatRepository),
+	)
 	if err != nil {
 		a.logger.Error().Err(err).Msg("telegram bot client init failed")
 		return err
@@ -123,27 +159,97 @@ func (a *App) initTelegram() error {
 	a.botClient = botClient
 	a.closerStack.Push(a.botClient)
 
-	telegramtransport.InitHandlers(a.logger, a.botClient, a.categoryRepository, a.sizeRepository, a.trackingRepository)
-	a.sender = telegramtransport.NewSender(a.botClient)
-	a.botClient.Start(a.ctx)
+	a.sender = telegramtransport.NewSender(a.botClient, a.config.SenderConfig)
+	a.closerStack.Push(a.sender)
 
 	return nil
 }
 
-func (a *App) initWorkers() {
+func (a *App) startTelegram() error {
+	telegramtransport.InitHandlers(a.logger, a.botClient, a.categoryRepository, a.sizeRepository, a.trackingRepository, a.stockRepository, a.regionRepository, a.worker, a.categoryService, a.productService)
+	telegramtransport.InitAdminHandlers(
+		a.logger,
+		a.botClient,
+		a.config.Admins,
+		a.worker,
+		a.sender,
+		a.statsRepository,
+		a.chatRepository,
+		a.categoryRepository,
+		a.productService,
+		a.categoryService,
+		a.regionRepository,
+		a.treeRepository,
+	)
+
+	if a.config.TelegramConfig.IsWebhook() {
+		a.initHTTPServer()
+	}
+
+	if err := a.botClient.Start(a.ctx); err != nil {
+		a.logger.Error().Err(err).Msg("telegram bot client start failed")
+		return err
+	}
+
+	return nil
+}
+
+func (a *App) initHTTPServer() {
+	mux := nethttp.NewServeMux()
+	mux.Handle(a.config.TelegramConfig.WebhookPath(), a.botClient.WebhookHandler())
+
+	handler := middleware.WithHandler(mux, middleware.Recovery(a.logger))
+
+	a.httpServer = http.NewServer(a.logger, a.config.HTTPConfig, a.fatalErrors, handler)
+	a.closerStack.Push(a.httpServer)
+	a.httpServer.Start()
+}
+
+func (a *App) initWorkers() error {
+	httpClient, err := http.NewClient(a.logger, a.config.HTTPClientConfig)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("http client init failed")
+		return err
+	}
+	// closed last so the cassette keeps the requests of crawls finishing on shutdown
+	a.closerStack.Push(httpClient)
+
+	a.cardClient = httptransport.NewCardClient(a.logger, a.config.CardClientConfig, httpClient.Client)
+	a.enrichmentService = service.NewEnrichmentService(a.logger, a.cardClient, a.detailsRepository)
+
+	// queue is closed after the worker so crawls still running on shutdown can push into it
+	a.enrichmentQueue = memory.NewQueue[model.ProductRef](a.ctx, a.logger, a.config.EnrichmentQueueConfig, a.enrichmentService.HandleProducts)
+	a.closerStack.Push(a.enrichmentQueue)
+
 	a.worker = background.NewWorker(a.logger)
 	a.closerStack.Push(a.worker)
 
-	a.productClient = httptransport.NewProductClient(a.logger, a.config.ProductsClientConfig, http.NewClient(a.config.HTTPClientConfig))
-	a.trackingService = service.NewTrackingService(a.logger, a.trackingRepository, a.sender)
+	a.productClient = httptransport.NewProductClient(a.logger, a.config.ProductsClientConfig, httpClient.Client)
+	a.catalogClient = httptransport.NewCatalogClient(a.logger, a.config.CatalogClientConfig, httpClient.Client)
+	a.ozonClient = httptransport.NewOzonClient(a.logger, a.config.OzonClientConfig, httpClient.Client)
+
+	a.marketplaces = service.NewMarketplaceRegistry()
+	a.marketplaces.Register(model.MarketplaceWildberries, a.productClient, a.catalogClient, "wildberries.ru", "wb.ru")
+	a.marketplaces.Register(model.MarketplaceOzon, a.ozonClient, a.ozonClient, "ozon.ru")
+	a.trackingService = service.NewTrackingService(a.logger, a.trackingRepository, a.stockRepository, a.sender)
 
 	a.productService = service.NewProductService(
 		a.logger,
-		a.productClient,
+		a.config.ProductServiceConfig,
+		a.marketplaces,
+		service.NewCrawlScheduler(a.config.SchedulerConfig),
 		a.categoryRepository,
 		a.productRepository,
+		a.regionRepository,
 		a.trackingService,
+		a.enrichmentQueue,
 	)
 
+	a.categoryService = service.NewCategoryService(a.logger, a.catalogClient, a.marketplaces, a.marketplaces, a.categoryRepository, a.brandRepository, a.treeRepository)
+
 	a.worker.RunWithInterval(a.ctx, "run updates", a.config.ParseInterval, a.productService.RunUpdateWorkers)
+	a.worker.RunWithInterval(a.ctx, "sync categories", a.config.CategorySyncInterval, a.categoryService.SyncCategoryTree)
+	a.worker.RunWithInterval(a.ctx, "http client stats", a.config.HTTPClientConfig.StatsInterval, httpClient.LogStats)
+
+	return nil
 }
@@ -4,9 +4,12 @@ import (
 	"time"
 
 	httpapp "github.com/iamsorryprincess/wildberries-bot/cmd/api/http"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/service"
+	telegramapp "github.com/iamsorryprincess/wildberries-bot/cmd/api/telegram"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/config"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/queue/memory"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/telegram"
 	"github.com/spf13/viper"
 )
@@ -16,21 +19,50 @@ type Config struct {
 
 	ParseInterval time.Duration `config:"parse_interval"`
 
+	CategorySyncInterval time.Duration `config:"category_sync_interval"`
+
+	ProductServiceConfig service.ProductServiceConfig `config:"products_service"`
+
+	SchedulerConfig service.SchedulerConfig `config:"scheduler"`
+
 	MysqlConfig mysql.Config `config:"mysql"`
 
 	HTTPClientConfig http.ClientConfig `config:"http_client"`
 
 	ProductsClientConfig httpapp.ProductClientConfig `config:"products_client"`
 
+	CatalogClientConfig httpapp.CatalogClientConfig `config:"catalog_client"`
+
+	OzonClientConfig httpapp.OzonClientConfig `config:"ozon_client"`
+
+	CardClientConfig httpapp.CardClientConfig `config:"card_client"`
+
+	EnrichmentQueueConfig memory.Config `config:"enrichment_queue"`
+
 	TelegramConfig telegram.Config `config:"telegram"`
 
+	SenderConfig telegramapp.SenderConfig `config:"sender"`
+
+	Admins []int64 `config:"admins"`
+
 	HTTPConfig http.ServerConfig `config:"http"`
 }
 
 func Init() (Config, error) {
 	return config.Load[Config](func() {
 		viper.SetDefault("loglevel", "info")
-		viper.SetDefault("parse_interval", "15m")
+		viper.SetDefault("parse_interval", "1m")
+		viper.SetDefault("category_sync_interval", "24h")
+
+		viper.SetDefault("products_service.concurrency", 3)
+		viper.SetDefault("products_service.max_pages", 50)
+		viper.SetDefault("products_service.chunk_size", 50)
+		viper.SetDefault("products_service.delist_after", 3)
+
+		viper.SetDefault("scheduler.min_interval", "5m")
+		viper.SetDefault("scheduler.max_interval", "2h")
+		viper.SetDefault("scheduler.idle_interval", "24h")
+		viper.SetDefault("scheduler.jitter", 0.2)
 
 		viper.SetDefault("mysql.max_open_connections", 5)
 		viper.SetDefault("mysql.max_idle_connections", 5)
@@ -46,11 +78,46 @@ func Init() (Config, error) {
 		viper.SetDefault("http_client.tls_handshake_timeout", 10*time.Second)
 		viper.SetDefault("http_client.response_header_timeout", 5*time.Second)
 		viper.SetDefault("http_client.expect_continue_timeout", 1*time.Second)
+		viper.SetDefault("http_client.circuit_breaker.failure_threshold", 5)
+		viper.SetDefault("http_client.circuit_breaker.cool_down", time.Minute)
+		viper.SetDefault("http_client.circuit_breaker.half_open_requests", 1)
+		viper.SetDefault("http_client.circuit_breaker.failure_statuses", []int{429, 500, 502, 503, 504})
+		viper.SetDefault("http_client.rate_limit.requests_per_second", 5)
+		viper.SetDefault("http_client.rate_limit.burst", 5)
+		viper.SetDefault("http_client.rate_limit.hosts", []string{"catalog.wb.ru", "search.wb.ru"})
+		viper.SetDefault("http_client.rate_limit.per_host_requests_per_second", 0)
+		viper.SetDefault("http_client.rate_limit.per_host_burst", 1)
+		viper.SetDefault("http_client.proxy.assignment", http.ProxyAssignmentSticky)
+		viper.SetDefault("http_client.proxy.eject_duration", 5*time.Minute)
+		viper.SetDefault("http_client.stats_interval", 5*time.Minute)
 
 		viper.SetDefault("products_client.retry_count", 3)
 		viper.SetDefault("products_client.retry_delay", time.Second)
+		viper.SetDefault("products_client.max_retry_delay", 30*time.Second)
+		viper.SetDefault("products_client.retry_statuses", []int{429, 500, 502, 503, 504})
+
+		viper.SetDefault("catalog_client.menu_url", "https://static-basket-01.wbbasket.ru/vol0/data/main-menu-ru-ru-v3.json")
+		viper.SetDefault("catalog_client.dest", -1257786)
+
+		viper.SetDefault("ozon_client.api_url", "https://www.ozon.ru/api/entrypoint-api.bx/page/json/v2")
+		viper.SetDefault("ozon_client.retry_count", 3)
+		viper.SetDefault("ozon_client.retry_delay", time.Second)
+		viper.SetDefault("ozon_client.max_retry_delay", 30*time.Second)
+		viper.SetDefault("ozon_client.retry_statuses", []int{429, 500, 502, 503, 504})
+
+		viper.SetDefault("card_client.retry_count", 2)
+		viper.SetDefault("card_client.retry_delay", time.Second)
+		viper.SetDefault("card_client.max_retry_delay", 30*time.Second)
+		viper.SetDefault("card_client.retry_statuses", []int{429, 500, 502, 503, 504})
+
+		viper.SetDefault("enrichment_queue.batch_size", 50)
+		viper.SetDefault("enrichment_queue.buffer_size", 10000)
+		viper.SetDefault("enrichment_queue.flush_interval", 10*time.Second)
+
+		viper.SetDefault("sender.messages_per_second", 25)
 
 		viper.SetDefault("http.port", "8080")
+		viper.SetDefault("http.shutdown_timeout", "10s")
 		viper.SetDefault("http.read_timeout", "10s")
 		viper.SetDefault("http.read_header_timeout", "5s")
 		viper.SetDefault("http.write_timeout", "30s")
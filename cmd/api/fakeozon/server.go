@@ -0,0 +1,149 @@
+package fakeozon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	apiPath          = "/api/entrypoint-api.bx/page/json/v2"
+	productURLFormat = "https://www.ozon.ru/product/%d/"
+
+	// the real widget key carries a layout id, clients match it by prefix
+	searchResultsWidget = "searchResultsV2-252189-default-1"
+)
+
+type Item struct {
+	SKU           uint64  `json:"sku"`
+	Name          string  `json:"name"`
+	Brand         string  `json:"brand"`
+	Rating        float32 `json:"rating"`
+	Size          string  `json:"size,omitempty"`
+	Price         string  `json:"price"`
+	OriginalPrice string  `json:"originalPrice,omitempty"`
+	Stock         uint32  `json:"stock"`
+}
+
+type searchResults struct {
+	Items []Item `json:"items"`
+}
+
+// page is the page composer format, widget states are json documents encoded as strings.
+type page struct {
+	WidgetStates map[string]string `json:"widgetStates"`
+}
+
+type pageKey struct {
+	category string
+	page     int
+}
+
+// Server serves category pages in the ozon page composer format. Pages that were never set are served without items,
+// which is how the real site ends a category.
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	pages    map[pageKey][]Item
+	requests map[pageKey]int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		pages:    make(map[pageKey][]Item),
+		requests: make(map[pageKey]int),
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) APIURL() string {
+	return s.server.URL + apiPath
+}
+
+func (s *Server) RequestURL(category string) string {
+	return fmt.Sprintf("%s?url=/category/%s/?page=%%d", s.APIURL(), category)
+}
+
+func (s *Server) ProductURL() string {
+	return productURLFormat
+}
+
+func (s *Server) SetPage(category string, pageNumber int, items []Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[pageKey{category: category, page: pageNumber}] = items
+}
+
+func (s *Server) Requests(category string, pageNumber int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[pageKey{category: category, page: pageNumber}]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != apiPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	pageURL, err := url.Parse(r.URL.Query().Get("url"))
+	if err != nil {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(pageURL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "category" {
+		http.NotFound(w, r)
+		return
+	}
+
+	pageNumber := 1
+	if value := pageURL.Query().Get("page"); value != "" {
+		if pageNumber, err = strconv.Atoi(value); err != nil || pageNumber < 1 {
+			http.Error(w, "invalid page", http.StatusBadRequest)
+			return
+		}
+	}
+
+	key := pageKey{category: parts[1], page: pageNumber}
+
+	s.mu.Lock()
+	s.requests[key]++
+	items := s.pages[key]
+	s.mu.Unlock()
+
+	if items == nil {
+		items = []Item{}
+	}
+
+	state, err := json.Marshal(searchResults{Items: items})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(page{WidgetStates: map[string]string{
+		"header-1":          `{"title":"ozon"}`,
+		searchResultsWidget: string(state),
+	}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,333 @@
+package fakewb
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+const productURLFormat = "https://www.wildberries.ru/catalog/%d/detail.aspx"
+
+type Price struct {
+	Basic     uint64 `json:"basic"`
+	Product   uint64 `json:"product"`
+	Total     uint64 `json:"total"`
+	Logistics uint64 `json:"logistics"`
+	Return    int    `json:"return"`
+}
+
+type Stock struct {
+	Warehouse uint64 `json:"wh"`
+	Quantity  uint32 `json:"qty"`
+}
+
+type Size struct {
+	Name   string  `json:"name"`
+	Stocks []Stock `json:"stocks"`
+	Price  Price   `json:"price"`
+}
+
+type Color struct {
+	Name string `json:"name"`
+}
+
+type Product struct {
+	ID      uint64  `json:"id"`
+	Name    string  `json:"name"`
+	Rating  float32 `json:"reviewRating"`
+	Brand   string  `json:"brand"`
+	BrandID uint64  `json:"brandId"`
+	Colors  []Color `json:"colors"`
+	Sizes   []Size  `json:"sizes"`
+}
+
+type page struct {
+	State          int `json:"state"`
+	Version        int `json:"version"`
+	PayloadVersion int `json:"payloadVersion"`
+	Data           struct {
+		Products []Product `json:"products"`
+	} `json:"data"`
+}
+
+// searchPage is the search.wb.ru format, it lists products at the top level.
+type searchPage struct {
+	State    int       `json:"state"`
+	Version  int       `json:"version"`
+	Products []Product `json:"products"`
+}
+
+// key prefixes keep search queries and brands apart from catalog shards in the pages map
+const (
+	searchKeyPrefix = "search:"
+	brandKeyPrefix  = "brand:"
+)
+
+type Fault struct {
+	Status     int
+	Body       string
+	RetryAfter string
+}
+
+var (
+	FaultNoContent       = Fault{Status: http.StatusNoContent}
+	FaultTooManyRequests = Fault{Status: http.StatusTooManyRequests, RetryAfter: "0"}
+	FaultMalformedJSON   = Fault{Status: http.StatusOK, Body: `{"data":{"products":[{"id":`}
+	FaultInternalError   = Fault{Status: http.StatusInternalServerError, Body: "internal error"}
+	FaultNullProducts    = Fault{Status: http.StatusOK, Body: `{"version":2,"data":{"products":null}}`}
+	FaultNullData        = Fault{Status: http.StatusOK, Body: `{"version":2,"data":null}`}
+	FaultNullBody        = Fault{Status: http.StatusOK, Body: `null`}
+)
+
+type pageKey struct {
+	category string
+	page     int
+}
+
+type regionPriceKey struct {
+	dest      int64
+	productID uint64
+	size      string
+}
+
+// Server serves catalog pages in the catalog.wb.ru format and search pages in the search.wb.ru format. Pages that were never set are served empty,
+// which is how the real catalog signals the end of a category.
+type Server struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	pages    map[pageKey][]Product
+	faults   map[pageKey][]Fault
+	requests map[pageKey]int
+	prices   map[regionPriceKey]uint64
+}
+
+func NewServer() *Server {
+	s := &Server{
+		pages:    make(map[pageKey][]Product),
+		faults:   make(map[pageKey][]Fault),
+		requests: make(map[pageKey]int),
+		prices:   make(map[regionPriceKey]uint64),
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+func (s *Server) RequestURL(category string) string {
+	return fmt.Sprintf("%s/catalog/%s/v2/catalog?curr=rub&page=%%d&sort=popular", s.server.URL, category)
+}
+
+func (s *Server) SearchURL() string {
+	return s.server.URL + "/exactmatch/ru/common/v9/search?curr=rub&page=%d&resultset=catalog&sort=popular"
+}
+
+func (s *Server) BrandURL(brandID uint64) string {
+	return fmt.Sprintf("%s/brands/v2/catalog?brand=%d&curr=rub&page=%%d&sort=popular", s.server.URL, brandID)
+}
+
+func (s *Server) ProductURL() string {
+	return productURLFormat
+}
+
+func (s *Server) SetPage(category string, pageNumber int, products []Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[pageKey{category: category, page: pageNumber}] = products
+}
+
+func (s *Server) SetSearchPage(query string, pageNumber int, products []Product) {
+	s.SetPage(searchKeyPrefix+query, pageNumber, products)
+}
+
+func (s *Server) SetBrandPage(brandID uint64, pageNumber int, products []Product) {
+	s.SetPage(brandKeyPrefix+strconv.FormatUint(brandID, 10), pageNumber, products)
+}
+
+func (s *Server) LoadFixture(category string, pageNumber int, name string) error {
+	data, err := fixtures.ReadFile("fixtures/" + name + ".json")
+	if err != nil {
+		return fmt.Errorf("fakewb read fixture %s error: %w", name, err)
+	}
+
+	var fixture page
+	if err = json.Unmarshal(data, &fixture); err != nil {
+		return fmt.Errorf("fakewb decode fixture %s error: %w", name, err)
+	}
+
+	s.SetPage(category, pageNumber, fixture.Data.Products)
+	return nil
+}
+
+func (s *Server) SetPrice(category string, productID uint64, sizeName string, total uint64) bool {
+	return s.updateSize(category, productID, sizeName, func(size *Size) {
+		size.Price.Product = total
+		size.Price.Total = total
+	})
+}
+
+func (s *Server) SetPriceComponents(category string, productID uint64, sizeName string, price Price) bool {
+	return s.updateSize(category, productID, sizeName, func(size *Size) {
+		size.Price = price
+	})
+}
+
+// SetRegionPrice overrides the total and product price of the size for requests with the dest parameter.
+func (s *Server) SetRegionPrice(dest int64, productID uint64, sizeName string, total uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[regionPriceKey{dest: dest, productID: productID, size: sizeName}] = total
+}
+
+// SetQuantity replaces the warehouse stocks of the size with a single warehouse holding quantity items.
+func (s *Server) SetQuantity(category string, productID uint64, sizeName string, quantity uint32) bool {
+	return s.updateSize(category, productID, sizeName, func(size *Size) {
+		size.Stocks = []Stock{{Warehouse: 507, Quantity: quantity}}
+	})
+}
+
+func (s *Server) updateSize(category string, productID uint64, sizeName string, update func(size *Size)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, products := range s.pages {
+		if key.category != category {
+			continue
+		}
+
+		for i := range products {
+			if products[i].ID != productID {
+				continue
+			}
+
+			for j := range products[i].Sizes {
+				if products[i].Sizes[j].Name == sizeName {
+					update(&products[i].Sizes[j])
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// Fail queues one-shot responses for the page, they are served in order before the page content.
+func (s *Server) Fail(category string, pageNumber int, faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := pageKey{category: category, page: pageNumber}
+	s.faults[key] = append(s.faults[key], faults...)
+}
+
+func (s *Server) Requests(category string, pageNumber int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[pageKey{category: category, page: pageNumber}]
+}
+
+func (s *Server) regionProducts(products []Product, destParam string) []Product {
+	dest, err := strconv.ParseInt(destParam, 10, 64)
+	if err != nil || len(s.prices) == 0 {
+		return products
+	}
+
+	result := make([]Product, len(products))
+	for i, product := range products {
+		product.Sizes = append([]Size(nil), product.Sizes...)
+		for j := range product.Sizes {
+			if total, ok := s.prices[regionPriceKey{dest: dest, productID: product.ID, size: product.Sizes[j].Name}]; ok {
+				product.Sizes[j].Price.Product = total
+				product.Sizes[j].Price.Total = total
+			}
+		}
+		result[i] = product
+	}
+
+	return result
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	var category string
+	isSearch := r.URL.Path == "/exactmatch/ru/common/v9/search"
+	switch {
+	case isSearch:
+		category = searchKeyPrefix + r.URL.Query().Get("query")
+	case r.URL.Path == "/brands/v2/catalog":
+		category = brandKeyPrefix + r.URL.Query().Get("brand")
+	default:
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[0] != "catalog" || parts[2] != "v2" || parts[3] != "catalog" {
+			http.NotFound(w, r)
+			return
+		}
+		category = parts[1]
+	}
+
+	pageNumber, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageNumber < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+
+	key := pageKey{category: category, page: pageNumber}
+
+	s.mu.Lock()
+	s.requests[key]++
+
+	var fault *Fault
+	if queue := s.faults[key]; len(queue) > 0 {
+		fault = &queue[0]
+		s.faults[key] = queue[1:]
+	}
+
+	products := s.regionProducts(s.pages[key], r.URL.Query().Get("dest"))
+	if products == nil {
+		products = []Product{}
+	}
+
+	var body []byte
+	if isSearch {
+		body, err = json.Marshal(searchPage{Version: 2, Products: products})
+	} else {
+		var response page
+		response.Version = 2
+		response.PayloadVersion = 2
+		response.Data.Products = products
+		body, err = json.Marshal(response)
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if fault != nil {
+		if fault.RetryAfter != "" {
+			w.Header().Set("Retry-After", fault.RetryAfter)
+		}
+		w.WriteHeader(fault.Status)
+		_, _ = w.Write([]byte(fault.Body))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,236 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	httppkg "github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	cardURLFormat   = "%s/vol%d/part%d/%d/info/ru/card.json"
+	sellerURLFormat = "https://static-basket-01.wbbasket.ru/vol0/data/supplier-by-id/%d.json"
+)
+
+// upper vol bounds of the wbbasket.ru shards, products with a greater vol live on the next shard
+var basketVolBounds = []uint64{
+	143, 287, 431, 719, 1007, 1061, 1115, 1169, 1313, 1601, 1655, 1919, 2045, 2189,
+	2405, 2621, 2837, 3053, 3269, 3485, 3701, 3917, 4133, 4349, 4565, 4877, 5189, 5501,
+}
+
+type CardClientConfig struct {
+	BasketURL     string        `config:"basket_url"`
+	SellerURL     string        `config:"seller_url"`
+	RetryCount    uint          `config:"retry_count"`
+	RetryDelay    time.Duration `config:"retry_delay"`
+	MaxRetryDelay time.Duration `config:"max_retry_delay"`
+	RetryStatuses []int         `config:"retry_statuses"`
+}
+
+type CardClient struct {
+	logger      log.Logger
+	config      CardClientConfig
+	client      *http.Client
+	retryPolicy *httppkg.RetryPolicy
+
+	mu      sync.Mutex
+	sellers map[uint64]string
+}
+
+func NewCardClient(logger log.Logger, config CardClientConfig, httpClient *http.Client) *CardClient {
+	return &CardClient{
+		logger: logger,
+		config: config,
+		client: httpClient,
+		retryPolicy: httppkg.NewRetryPolicy(logger, httppkg.RetryConfig{
+			Count:    config.RetryCount,
+			Delay:    config.RetryDelay,
+			MaxDelay: config.MaxRetryDelay,
+			Statuses: config.RetryStatuses,
+		}),
+		sellers: make(map[uint64]string),
+	}
+}
+
+type cardResponse struct {
+	Description  string `json:"description"`
+	Compositions []struct {
+		Name string `json:"name"`
+	} `json:"compositions"`
+	Options []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"options"`
+	Media struct {
+		PhotoCount int `json:"photo_count"`
+	} `json:"media"`
+	Selling struct {
+		SupplierID uint64 `json:"supplier_id"`
+	} `json:"selling"`
+	SizesTable struct {
+		DetailsProps []string `json:"details_props"`
+		Values       []struct {
+			TechSize string   `json:"tech_size"`
+			Details  []string `json:"details"`
+		} `json:"values"`
+	} `json:"sizes_table"`
+}
+
+type sellerResponse struct {
+	SupplierName string `json:"supplierName"`
+	Trademark    string `json:"trademark"`
+}
+
+func (c *CardClient) GetProductDetails(ctx context.Context, productID uint64) (model.ProductDetails, error) {
+	vol := productID / 100000
+	part := productID / 1000
+
+	var card cardResponse
+	if err := c.getJSON(ctx, fmt.Sprintf(cardURLFormat, c.basketURL(vol), vol, part, productID), &card); err != nil {
+		return model.ProductDetails{}, err
+	}
+
+	details := model.ProductDetails{
+		ProductID:   productID,
+		Description: card.Description,
+		SellerID:    card.Selling.SupplierID,
+		ImagesCount: card.Media.PhotoCount,
+	}
+
+	compositions := make([]string, 0, len(card.Compositions))
+	for _, composition := range card.Compositions {
+		compositions = append(compositions, composition.Name)
+	}
+	details.Composition = strings.Join(compositions, ", ")
+
+	if details.Composition == "" {
+		for _, option := range card.Options {
+			if option.Name == "Состав" {
+				details.Composition = option.Value
+				break
+			}
+		}
+	}
+
+	for _, value := range card.SizesTable.Values {
+		row := model.SizeChartRow{
+			Size:   value.TechSize,
+			Values: make(map[string]string, len(value.Details)),
+		}
+
+		for i, detail := range value.Details {
+			if i < len(card.SizesTable.DetailsProps) {
+				row.Values[card.SizesTable.DetailsProps[i]] = detail
+			}
+		}
+
+		details.SizeChart = append(details.SizeChart, row)
+	}
+
+	if details.SellerID > 0 {
+		sellerName, err := c.getSellerName(ctx, details.SellerID)
+		if err != nil {
+			c.logger.Warn().Err(err).Uint64("seller_id", details.SellerID).Msg("CardClient failed get seller name")
+		}
+		details.SellerName = sellerName
+	}
+
+	return details, nil
+}
+
+func (c *CardClient) getSellerName(ctx context.Context, sellerID uint64) (string, error) {
+	c.mu.Lock()
+	name, ok := c.sellers[sellerID]
+	c.mu.Unlock()
+
+	if ok {
+		return name, nil
+	}
+
+	sellerURL := sellerURLFormat
+	if c.config.SellerURL != "" {
+		sellerURL = c.config.SellerURL
+	}
+
+	var seller sellerResponse
+	if err := c.getJSON(ctx, fmt.Sprintf(sellerURL, sellerID), &seller); err != nil {
+		if errors.Is(err, model.ErrProductCardNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	name = seller.Trademark
+	if name == "" {
+		name = seller.SupplierName
+	}
+
+	c.mu.Lock()
+	c.sellers[sellerID] = name
+	c.mu.Unlock()
+
+	return name, nil
+}
+
+func (c *CardClient) basketURL(vol uint64) string {
+	if c.config.BasketURL != "" {
+		return c.config.BasketURL
+	}
+
+	basket := len(basketVolBounds) + 1
+	for i, bound := range basketVolBounds {
+		if vol <= bound {
+			basket = i + 1
+			break
+		}
+	}
+
+	return fmt.Sprintf("https://basket-%02d.wbbasket.ru", basket)
+}
+
+func (c *CardClient) getJSON(ctx context.Context, url string, result interface{}) error {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("CardClient making http request error: %w", err)
+	}
+
+	httpRequest.Header.Add("Accept", "*/*")
+
+	httpResponse, err := c.retryPolicy.Do(c.client, httpRequest)
+	if err != nil {
+		if errors.Is(err, httppkg.ErrCircuitOpen) {
+			return model.ErrCircuitOpen
+		}
+		return fmt.Errorf("CardClient making http request error: %w", err)
+	}
+
+	defer func() {
+		if cErr := httpResponse.Body.Close(); cErr != nil {
+			c.logger.Warn().Err(cErr).Msg("CardClient failed to close response body")
+		}
+	}()
+
+	switch httpResponse.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return model.ErrProductCardNotFound
+	case http.StatusTooManyRequests:
+		return model.ErrRequestLimit
+	default:
+		return fmt.Errorf("CardClient http status is not ok; status: %d", httpResponse.StatusCode)
+	}
+
+	if err = json.NewDecoder(httpResponse.Body).Decode(result); err != nil {
+		return fmt.Errorf("CardClient decode http response body error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	testCardPath   = "/vol1234/part123456/123456789/info/ru/card.json"
+	testSellerPath = "/seller/42.json"
+)
+
+// cardServer serves card and seller responses by path and counts requests per path.
+type cardServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]string
+	statuses  map[string]int
+	requests  map[string]int
+}
+
+func newCardServer(t *testing.T) *cardServer {
+	t.Helper()
+
+	server := &cardServer{
+		responses: make(map[string]string),
+		statuses:  make(map[string]int),
+		requests:  make(map[string]int),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		server.requests[r.URL.Path]++
+		if status, ok := server.statuses[r.URL.Path]; ok {
+			w.WriteHeader(status)
+			return
+		}
+
+		body, ok := server.responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func (s *cardServer) set(path string, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[path] = body
+}
+
+func (s *cardServer) fail(path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[path] = status
+}
+
+func (s *cardServer) requestsCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func newTestCardClient(server *cardServer) *CardClient {
+	return NewCardClient(log.NewNop(), CardClientConfig{
+		BasketURL: server.URL,
+		SellerURL: server.URL + "/seller/%d.json",
+	}, &http.Client{Timeout: 5 * time.Second})
+}
+
+func TestCardClientGetProductDetails(t *testing.T) {
+	server := newCardServer(t)
+	server.set(testCardPath, `{
+  "description": "Платье из льна",
+  "compositions": [{"name": "лён 70%"}, {"name": "хлопок 30%"}],
+  "options": [{"name": "Состав", "value": "не используется"}],
+  "media": {"photo_count": 7},
+  "selling": {"supplier_id": 42},
+  "sizes_table": {
+    "details_props": ["Обхват груди", "Обхват талии"],
+    "values": [{"tech_size": "42", "details": ["84", "66"]}, {"tech_size": "44", "details": ["88", "70", "лишнее"]}]
+  }
+}`)
+	server.set(testSellerPath, `{"supplierName": "ИП Иванова", "trademark": "Zarina"}`)
+
+	client := newTestCardClient(server)
+
+	details, err := client.GetProductDetails(context.Background(), 123456789)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := model.ProductDetails{
+		ProductID:   123456789,
+		Description: "Платье из льна",
+		Composition: "лён 70%, хлопок 30%",
+		SellerID:    42,
+		SellerName:  "Zarina",
+		ImagesCount: 7,
+		SizeChart: []model.SizeChartRow{
+			{Size: "42", Values: map[string]string{"Обхват груди": "84", "Обхват талии": "66"}},
+			{Size: "44", Values: map[string]string{"Обхват груди": "88", "Обхват талии": "70"}},
+		},
+	}
+	if !reflect.DeepEqual(details, expected) {
+		t.Errorf("expected %+v, got %+v", expected, details)
+	}
+
+	if _, err = client.GetProductDetails(context.Background(), 123456789); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if count := server.requestsCount(testSellerPath); count != 1 {
+		t.Errorf("expected the seller name cached, got %d seller requests", count)
+	}
+}
+
+func TestCardClientSellerFallbacks(t *testing.T) {
+	tests := []struct {
+		name   string
+		seller string
+		status int
+		card   string
+		want   model.ProductDetails
+	}{
+		{
+			name:   "composition from options and supplier name",
+			card:   `{"options": [{"name": "Цвет", "value": "синий"}, {"name": "Состав", "value": "вискоза"}], "selling": {"supplier_id": 42}}`,
+			seller: `{"supplierName": "ИП Иванова"}`,
+			want:   model.ProductDetails{ProductID: 123456789, Composition: "вискоза", SellerID: 42, SellerName: "ИП Иванова"},
+		},
+		{
+			name:   "seller not found",
+			card:   `{"selling": {"supplier_id": 42}}`,
+			status: http.StatusNotFound,
+			want:   model.ProductDetails{ProductID: 123456789, SellerID: 42},
+		},
+		{
+			name:   "seller failed",
+			card:   `{"selling": {"supplier_id": 42}}`,
+			status: http.StatusBadGateway,
+			want:   model.ProductDetails{ProductID: 123456789, SellerID: 42},
+		},
+		{
+			name: "no seller",
+			card: `{"description": "Платье"}`,
+			want: model.ProductDetails{ProductID: 123456789, Description: "Платье"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCardServer(t)
+			server.set(testCardPath, tt.card)
+			if tt.seller != "" {
+				server.set(testSellerPath, tt.seller)
+			}
+			if tt.status != 0 {
+				server.fail(testSellerPath, tt.status)
+			}
+
+			details, err := newTestCardClient(server).GetProductDetails(context.Background(), 123456789)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(details, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, details)
+			}
+		})
+	}
+}
+
+func TestCardClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+	}{
+		{name: "card not found", err: model.ErrProductCardNotFound},
+		{name: "request limit", status: http.StatusTooManyRequests, err: model.ErrRequestLimit},
+		{name: "bad status", status: http.StatusInternalServerError},
+		{name: "bad body", body: `{"description": `},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCardServer(t)
+			if tt.body != "" {
+				server.set(testCardPath, tt.body)
+			}
+			if tt.status != 0 {
+				server.fail(testCardPath, tt.status)
+			}
+
+			_, err := newTestCardClient(server).GetProductDetails(context.Background(), 123456789)
+			if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestCardClientBasketURL(t *testing.T) {
+	client := NewCardClient(log.NewNop(), CardClientConfig{}, http.DefaultClient)
+
+	tests := []struct {
+		vol  uint64
+		want string
+	}{
+		{vol: 0, want: "https://basket-01.wbbasket.ru"},
+		{vol: 143, want: "https://basket-01.wbbasket.ru"},
+		{vol: 144, want: "https://basket-02.wbbasket.ru"},
+		{vol: 1234, want: "https://basket-09.wbbasket.ru"},
+		{vol: 5501, want: "https://basket-28.wbbasket.ru"},
+		{vol: 5502, want: "https://basket-29.wbbasket.ru"},
+	}
+
+	for _, tt := range tests {
+		if got := client.basketURL(tt.vol); got != tt.want {
+			t.Errorf("vol %d: expected %s, got %s", tt.vol, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	catalogRequestURLFormat = "https://catalog.wb.ru/catalog/%s/v2/catalog?ab_testing=false&appType=1&cat=%d&curr=rub&dest=%d&hide_dtype=13&lang=ru&page=%%d&sort=popular&spp=30"
+	catalogProductURL       = "https://www.wildberries.ru/catalog/%d/detail.aspx"
+	searchRequestURLFormat  = "https://search.wb.ru/exactmatch/ru/common/v9/search?ab_testing=false&appType=1&curr=rub&dest=%d&lang=ru&page=%%d&resultset=catalog&sort=popular&spp=30"
+	brandRequestURLFormat   = "https://catalog.wb.ru/brands/v2/catalog?ab_testing=false&appType=1&brand=%d&curr=rub&dest=%d&lang=ru&page=%%d&sort=popular&spp=30"
+	sellerRequestURLFormat  = "https://catalog.wb.ru/sellers/v2/catalog?ab_testing=false&appType=1&curr=rub&dest=%d&lang=ru&page=%%d&sort=popular&spp=30&supplier=%d"
+	searchEmoji             = "🔎"
+	brandEmoji              = "🏷"
+	sellerEmoji             = "🏪"
+	searchNamePrefix        = "q_"
+	brandNamePrefix         = "b_"
+	sellerNamePrefix        = "s_"
+	sourceTitleLength       = 20
+)
+
+type CatalogClientConfig struct {
+	MenuURL string `config:"menu_url"`
+	Dest    int64  `config:"dest"`
+}
+
+type CatalogClient struct {
+	logger log.Logger
+	config CatalogClientConfig
+	client *http.Client
+}
+
+func NewCatalogClient(logger log.Logger, config CatalogClientConfig, httpClient *http.Client) *CatalogClient {
+	return &CatalogClient{
+		logger: logger,
+		config: config,
+		client: httpClient,
+	}
+}
+
+type menuItem struct {
+	ID     uint64     `json:"id"`
+	Name   string     `json:"name"`
+	URL    string     `json:"url"`
+	Shard  string     `json:"shard"`
+	Query  string     `json:"query"`
+	Childs []menuItem `json:"childs"`
+}
+
+func (c *CatalogClient) ResolveCategory(ctx context.Context, pageURL string) (model.Category, error) {
+	path, err := parseCatalogPath(pageURL)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	items, err := c.getMenu(ctx)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	item, ok := findMenuItem(items, path)
+	if !ok {
+		return model.Category{}, model.ErrCategoryNotFound
+	}
+
+	node, err := menuNode(item, 0, 0)
+	if err != nil {
+		return model.Category{}, fmt.Errorf("CatalogClient.ResolveCategory category %s: %w", path, err)
+	}
+
+	return c.ResolveNode(node)
+}
+
+// ResolveNode describes the synced menu node as a catalog category, siblings share the shard so the name takes the cat id too.
+func (c *CatalogClient) ResolveNode(node model.CategoryNode) (model.Category, error) {
+	if node.Shard == "" || node.CatalogID == 0 {
+		return model.Category{}, fmt.Errorf("CatalogClient.ResolveNode node %d has no catalog shard", node.ID)
+	}
+
+	return model.Category{
+		Type:        model.CategoryTypeCatalog,
+		Marketplace: model.MarketplaceWildberries,
+		Name:        fmt.Sprintf("%s_%d", node.Shard, node.CatalogID),
+		Title:       node.Name,
+		RequestURL:  fmt.Sprintf(catalogRequestURLFormat, node.Shard, node.CatalogID, c.config.Dest),
+		ProductURL:  catalogProductURL,
+	}, nil
+}
+
+// GetCategoryTree downloads the catalog menu and flattens it into nodes, parents go before their children.
+func (c *CatalogClient) GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error) {
+	items, err := c.getMenu(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []model.CategoryNode
+	var walk func(items []menuItem, parentID uint64)
+	walk = func(items []menuItem, parentID uint64) {
+		for i, item := range items {
+			node, err := menuNode(item, parentID, i)
+			if err != nil {
+				// grouping items and promo pages have no catalog, they still hold the tree together
+				c.logger.Debug().Err(err).Uint64("menu_id", item.ID).Msg("menu item without catalog")
+			}
+
+			nodes = append(nodes, node)
+			walk(item.Childs, item.ID)
+		}
+	}
+	walk(items, 0)
+
+	return nodes, nil
+}
+
+// ResolveSearch describes the search query as a category, equal queries resolve to the same name.
+func (c *CatalogClient) ResolveSearch(query string) model.Category {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	hash := sha1.Sum([]byte(query))
+
+	return model.Category{
+		Type:        model.CategoryTypeSearch,
+		Marketplace: model.MarketplaceWildberries,
+		Name:        searchNamePrefix + hex.EncodeToString(hash[:])[:16],
+		Title:       sourceTitle(query),
+		Emoji:       searchEmoji,
+		RequestURL:  fmt.Sprintf(searchRequestURLFormat, c.config.Dest),
+		ProductURL:  catalogProductURL,
+		Query:       query,
+	}
+}
+
+// ResolveBrand describes the brand as a category, the name is only used for the title.
+func (c *CatalogClient) ResolveBrand(brand model.Brand) model.Category {
+	title := brand.Name
+	if title == "" {
+		title = fmt.Sprintf("бренд %d", brand.ID)
+	}
+
+	return model.Category{
+		Type:        model.CategoryTypeBrand,
+		Marketplace: model.MarketplaceWildberries,
+		Name:        fmt.Sprintf("%s%d", brandNamePrefix, brand.ID),
+		Title:       sourceTitle(title),
+		Emoji:       brandEmoji,
+		RequestURL:  fmt.Sprintf(brandRequestURLFormat, brand.ID, c.config.Dest),
+		ProductURL:  catalogProductURL,
+	}
+}
+
+// ResolveSeller describes the seller as a category, the name is only used for the title.
+func (c *CatalogClient) ResolveSeller(seller model.Seller) model.Category {
+	title := seller.Name
+	if title == "" {
+		title = fmt.Sprintf("продавец %d", seller.ID)
+	}
+
+	return model.Category{
+		Type:        model.CategoryTypeSeller,
+		Marketplace: model.MarketplaceWildberries,
+		Name:        fmt.Sprintf("%s%d", sellerNamePrefix, seller.ID),
+		Title:       sourceTitle(title),
+		Emoji:       sellerEmoji,
+		RequestURL:  fmt.Sprintf(sellerRequestURLFormat, c.config.Dest, seller.ID),
+		ProductURL:  catalogProductURL,
+	}
+}
+
+// sourceTitle is shown on buttons and passed through callback data, it has to stay short and free of separators.
+func sourceTitle(title string) string {
+	title = strings.NewReplacer(":", " ", "/", " ").Replace(title)
+	if utf8.RuneCountInString(title) > sourceTitleLength {
+		title = string([]rune(title)[:sourceTitleLength-1]) + "…"
+	}
+
+	return title
+}
+
+func (c *CatalogClient) getMenu(ctx context.Context) ([]menuItem, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.MenuURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("CatalogClient.getMenu making http request error: %w", err)
+	}
+
+	httpRequest.Header.Add("Accept", "*/*")
+
+	httpResponse, err := c.client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("CatalogClient.getMenu making http request error: %w", err)
+	}
+
+	defer func() {
+		if cErr := httpResponse.Body.Close(); cErr != nil {
+			c.logger.Warn().Err(cErr).Msg("CatalogClient.getMenu failed to close response body")
+		}
+	}()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CatalogClient.getMenu http status is not ok; status: %d", httpResponse.StatusCode)
+	}
+
+	var items []menuItem
+	if err = json.NewDecoder(httpResponse.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("CatalogClient.getMenu decode http response body error: %w", err)
+	}
+
+	return items, nil
+}
+
+// menuNode converts the menu item, the node is returned without a catalog id when the item query has none.
+func menuNode(item menuItem, parentID uint64, position int) (model.CategoryNode, error) {
+	node := model.CategoryNode{
+		ID:       item.ID,
+		ParentID: parentID,
+		Name:     item.Name,
+		URL:      item.URL,
+		Shard:    item.Shard,
+		Position: position,
+	}
+
+	if item.Shard == "" {
+		return node, errors.New("menu item has no catalog shard")
+	}
+
+	query, err := url.ParseQuery(item.Query)
+	if err != nil {
+		return node, fmt.Errorf("parse menu item query error: %w", err)
+	}
+
+	node.CatalogID, err = strconv.ParseUint(query.Get("cat"), 10, 64)
+	if err != nil {
+		return node, fmt.Errorf("parse menu item cat id error: %w", err)
+	}
+
+	return node, nil
+}
+
+func parseCatalogPath(pageURL string) (string, error) {
+	parsedURL, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid catalog url %q: %w", pageURL, err)
+	}
+
+	host := parsedURL.Hostname()
+	if host != "wildberries.ru" && !strings.HasSuffix(host, ".wildberries.ru") {
+		return "", fmt.Errorf("invalid catalog url %q: not a wildberries host", pageURL)
+	}
+
+	path := strings.TrimSuffix(parsedURL.Path, "/")
+	if !strings.HasPrefix(path, "/catalog/") {
+		return "", fmt.Errorf("invalid catalog url %q: not a catalog page", pageURL)
+	}
+
+	return path, nil
+}
+
+func findMenuItem(items []menuItem, path string) (menuItem, bool) {
+	for _, item := range items {
+		if strings.TrimSuffix(item.URL, "/") == path {
+			return item, true
+		}
+
+		if found, ok := findMenuItem(item.Childs, path); ok {
+			return found, true
+		}
+	}
+
+	return menuItem{}, false
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const testMenu = `[
+  {"id": 306, "name": "Женщинам", "url": "/catalog/zhenshchinam", "childs": [
+    {"id": 8126, "name": "Платья", "url": "/catalog/zhenshchinam/odezhda/platya", "shard": "bl_shirts", "query": "cat=8126"},
+    {"id": 8127, "name": "Юбки", "url": "/catalog/zhenshchinam/odezhda/yubki", "shard": "bl_skirts", "query": "cat=8127"}
+  ]},
+  {"id": 566, "name": "Обувь", "url": "/catalog/obuv", "shard": "shoes", "query": "cat=566"}
+]`
+
+func TestCatalogClientGetCategoryTree(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(testMenu))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewCatalogClient(log.NewNop(), CatalogClientConfig{MenuURL: server.URL, Dest: -1257786}, server.Client())
+
+	nodes, err := client.GetCategoryTree(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []model.CategoryNode{
+		{ID: 306, Name: "Женщинам", URL: "/catalog/zhenshchinam"},
+		{ID: 8126, ParentID: 306, Name: "Платья", URL: "/catalog/zhenshchinam/odezhda/platya", Shard: "bl_shirts", CatalogID: 8126},
+		{ID: 8127, ParentID: 306, Name: "Юбки", URL: "/catalog/zhenshchinam/odezhda/yubki", Shard: "bl_skirts", CatalogID: 8127, Position: 1},
+		{ID: 566, Name: "Обувь", URL: "/catalog/obuv", Shard: "shoes", CatalogID: 566, Position: 1},
+	}
+
+	if len(nodes) != len(expected) {
+		t.Fatalf("expected %d nodes, got %+v", len(expected), nodes)
+	}
+
+	for i := range expected {
+		if nodes[i] != expected[i] {
+			t.Errorf("node %d: expected %+v, got %+v", i, expected[i], nodes[i])
+		}
+	}
+
+	if _, err = client.ResolveNode(nodes[0]); err == nil {
+		t.Error("expected error for node without catalog")
+	}
+
+	category, err := client.ResolveNode(nodes[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if category.Name != "bl_shirts_8126" || category.Title != "Платья" || category.Type != model.CategoryTypeCatalog {
+		t.Errorf("unexpected category: %+v", category)
+	}
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,18 +11,24 @@ import (
 	"time"
 
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	httppkg "github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
 )
 
+var errUnexpectedToken = errors.New("unexpected token")
+
 type ProductClientConfig struct {
-	RetryCount uint          `config:"retry_count"`
-	RetryDelay time.Duration `config:"retry_delay"`
+	RetryCount    uint          `config:"retry_count"`
+	RetryDelay    time.Duration `config:"retry_delay"`
+	MaxRetryDelay time.Duration `config:"max_retry_delay"`
+	RetryStatuses []int         `config:"retry_statuses"`
 }
 
 type ProductClient struct {
-	logger log.Logger
-	config ProductClientConfig
-	client *http.Client
+	logger      log.Logger
+	config      ProductClientConfig
+	client      *http.Client
+	retryPolicy *httppkg.RetryPolicy
 }
 
 func NewProductClient(logger log.Logger, config ProductClientConfig, httpClient *http.Client) *ProductClient {
@@ -29,150 +36,282 @@ func NewProductClient(logger log.Logger, config ProductClientConfig, httpClient
 		logger: logger,
 		config: config,
 		client: httpClient,
+		retryPolicy: httppkg.NewRetryPolicy(logger, httppkg.RetryConfig{
+			Count:    config.RetryCount,
+			Delay:    config.RetryDelay,
+			MaxDelay: config.MaxRetryDelay,
+			Statuses: config.RetryStatuses,
+		}),
 	}
 }
 
-type response struct {
-	State          int `json:"state"`
-	Version        int `json:"version"`
-	PayloadVersion int `json:"payloadVersion"`
-	Data           struct {
-		Products []struct {
-			ID     uint64  `json:"id"`
-			Name   string  `json:"name"`
-			Rating float32 `json:"reviewRating"`
-
-			Brand   string `json:"brand"`
-			BrandID uint64 `json:"brandId"`
-
-			Colors []struct {
-				Name string `json:"name"`
-			} `json:"colors"`
-
-			Sizes []struct {
-				Name  string `json:"name"`
-				Price struct {
-					Basic     float32 `json:"basic"`
-					Product   float32 `json:"product"`
-					Total     float32 `json:"total"`
-					Logistics float32 `json:"logistics"`
-					Return    int     `json:"return"`
-				} `json:"price"`
-			} `json:"sizes"`
-		} `json:"products"`
-	} `json:"data"`
+type responseProduct struct {
+	ID     uint64  `json:"id"`
+	Name   string  `json:"name"`
+	Rating float32 `json:"reviewRating"`
+
+	Brand   string `json:"brand"`
+	BrandID uint64 `json:"brandId"`
+
+	Colors []struct {
+		Name string `json:"name"`
+	} `json:"colors"`
+
+	Sizes []struct {
+		Name   string `json:"name"`
+		Stocks []struct {
+			Warehouse uint64 `json:"wh"`
+			Quantity  uint32 `json:"qty"`
+		} `json:"stocks"`
+		Price struct {
+			Basic     model.Money `json:"basic"`
+			Product   model.Money `json:"product"`
+			Total     model.Money `json:"total"`
+			Logistics model.Money `json:"logistics"`
+			Return    model.Money `json:"return"`
+		} `json:"price"`
+	} `json:"sizes"`
 }
 
-func (c *ProductClient) GetProducts(_ context.Context, request model.ProductsRequest) ([]model.Product, error) {
-	url := fmt.Sprintf(request.RequestURL, request.Category, request.Page)
-	httpRequest, err := http.NewRequest(http.MethodGet, url, nil)
+// StreamProducts requests one page and passes its products to yield one by one as they are decoded,
+// the page is never held in memory as a whole.
+func (c *ProductClient) StreamProducts(ctx context.Context, request model.ProductsRequest, yield func(product model.Product) error) error {
+	ctx = httppkg.WithRouteKey(ctx, request.Category)
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL(request), nil)
 	if err != nil {
-		return nil, fmt.Errorf("ProductClient.GetData making http request error: %w", err)
+		return fmt.Errorf("ProductClient.StreamProducts making http request error: %w", err)
+	}
+
+	query := httpRequest.URL.Query()
+	if request.Type == model.CategoryTypeSearch {
+		query.Set("query", request.Query)
+	}
+
+	// dest in the category url is only a fallback, prices are crawled for the region of the request
+	if request.Dest != 0 {
+		query.Set("dest", strconv.FormatInt(request.Dest, 10))
 	}
+	httpRequest.URL.RawQuery = query.Encode()
 
 	httpRequest.Header.Add("Accept", "*/*")
 	httpRequest.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
 
-	var httpResponse *http.Response
-	var retryCount uint
-
-	for {
-		httpResponse, err = c.client.Do(httpRequest)
-		if err != nil {
-			return nil, fmt.Errorf("ProductClient.GetData making http request error: %w", err)
+	httpResponse, err := c.retryPolicy.Do(c.client, httpRequest)
+	if err != nil {
+		if errors.Is(err, httppkg.ErrCircuitOpen) {
+			return model.ErrCircuitOpen
 		}
-
-		if httpResponse.StatusCode == http.StatusTooManyRequests ||
-			httpResponse.StatusCode == http.StatusGatewayTimeout ||
-			httpResponse.StatusCode == http.StatusServiceUnavailable {
-			if retryCount == c.config.RetryCount {
-				break
-			}
-
-			c.logger.Warn().
-				Int("status", httpResponse.StatusCode).
-				Uint("try", retryCount+1).
-				Msg("ProductClient.GetData response bad status trying to retry")
-
-			if err = httpResponse.Body.Close(); err != nil {
-				c.logger.Warn().Err(err).Msg("ProductClient.GetData failed to close response body")
-			}
-
-			time.Sleep(c.config.RetryDelay * (1 + time.Duration(retryCount)))
-			retryCount++
-			continue
+		if errors.Is(err, httppkg.ErrNoProxyAvailable) {
+			return model.ErrRequestLimit
 		}
-
-		break
+		return fmt.Errorf("ProductClient.StreamProducts making http request error: %w", err)
 	}
 
 	defer func() {
 		if cErr := httpResponse.Body.Close(); cErr != nil {
-			c.logger.Warn().Err(cErr).Msg("ProductClient.GetData failed to close response body")
+			c.logger.Warn().Err(cErr).Msg("ProductClient.StreamProducts failed to close response body")
 		}
 	}()
 
 	if httpResponse.StatusCode == http.StatusNoContent {
-		return nil, nil
+		return nil
 	}
 
 	if httpResponse.StatusCode != http.StatusOK {
 		if httpResponse.StatusCode == http.StatusTooManyRequests {
-			return nil, model.ErrRequestLimit
+			return model.ErrRequestLimit
 		}
 
 		body, rErr := io.ReadAll(httpResponse.Body)
 		if rErr != nil {
-			c.logger.Error().Err(rErr).Msg("ProductClient.GetData failed to read response body")
+			c.logger.Error().Err(rErr).Msg("ProductClient.StreamProducts failed to read response body")
 		}
 
 		c.logger.Error().Int("status", httpResponse.StatusCode).Str("body", string(body)).Send()
-		return nil, fmt.Errorf("ProductClient.GetData http status is not ok; status: %d", httpResponse.StatusCode)
-	}
-
-	var respData response
-	if err = json.NewDecoder(httpResponse.Body).Decode(&respData); err != nil {
-		return nil, fmt.Errorf("ProductClient.GetData decode http request body error: %w", err)
+		return fmt.Errorf("ProductClient.StreamProducts http status is not ok; status: %d", httpResponse.StatusCode)
 	}
 
-	var result []model.Product
-	for _, item := range respData.Data.Products {
-		colors := make([]string, 0, len(item.Colors))
-		for _, color := range item.Colors {
-			colors = append(colors, color.Name)
+	var yieldErr error
+	if err = decodeProducts(httpResponse.Body, func(item responseProduct) error {
+		yieldErr = yield(newProduct(request, item))
+		return yieldErr
+	}); err != nil {
+		// errors of yield are the caller's own, they are passed through untouched
+		if yieldErr != nil {
+			return yieldErr
 		}
+		if isFormatError(err) {
+			return fmt.Errorf("ProductClient.StreamProducts decode http response body: %w: %w", model.ErrMalformedPage, err)
+		}
+		return fmt.Errorf("ProductClient.StreamProducts read http response body: %w", err)
+	}
 
-		product := model.Product{
-			ID:         item.ID,
-			CategoryID: request.CategoryID,
-			Name:       item.Name,
-			Rating:     item.Rating,
-			URL:        fmt.Sprintf(request.ProductURL, item.ID),
+	return nil
+}
 
-			Brand:   item.Brand,
-			BrandID: item.BrandID,
+// decodeProducts walks the response token by token and decodes products one at a time, it accepts both the catalog format
+// with products inside data and the search format with top level products. Other fields are skipped without decoding.
+func decodeProducts(body io.Reader, yield func(item responseProduct) error) error {
+	decoder := json.NewDecoder(body)
 
-			Colors: colors,
+	var walk func(depth int) error
+	walk = func(depth int) error {
+		if ok, err := openDelim(decoder, '{'); err != nil || !ok {
+			return err
 		}
 
-		for _, size := range item.Sizes {
-			priceValue := fmt.Sprintf("%.f", size.Price.Total)
-			strPriceValue := priceValue[:len(priceValue)-2]
-			floatPriceValue, pErr := strconv.ParseFloat(strPriceValue, 32)
-			if pErr != nil {
-				return nil, fmt.Errorf("ProductClient.GetData parse product:%d price error: %w", item.ID, err)
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
 			}
 
-			size := model.ProductSize{
-				Name:         size.Name,
-				CurrentPrice: float32(floatPriceValue),
+			switch key, _ := token.(string); {
+			case key == "products":
+				err = decodeProductsArray(decoder, yield)
+			case key == "data" && depth == 0:
+				err = walk(depth + 1)
+			default:
+				err = skipValue(decoder)
 			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return expectDelim(decoder, '}')
+	}
+
+	return walk(0)
+}
+
+// isFormatError tells a body that is not a products page from a body that failed to be read,
+// a body cut short is malformed whatever cut it.
+func isFormatError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, errUnexpectedToken)
+}
+
+func decodeProductsArray(decoder *json.Decoder, yield func(item responseProduct) error) error {
+	if ok, err := openDelim(decoder, '['); err != nil || !ok {
+		return err
+	}
+
+	for decoder.More() {
+		var item responseProduct
+		if err := decoder.Decode(&item); err != nil {
+			return err
+		}
+
+		if err := yield(item); err != nil {
+			return err
+		}
+	}
 
-			product.Sizes = append(product.Sizes, size)
+	return expectDelim(decoder, ']')
+}
+
+// openDelim reads the opening delimiter of an object or an array, it returns false for null which stands for an empty page
+// the same way decoding null into a struct or a slice leaves it empty.
+func openDelim(decoder *json.Decoder, delim json.Delim) (bool, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return false, err
+	}
+
+	if token == nil {
+		return false, nil
+	}
+
+	if token != delim {
+		return false, fmt.Errorf("%w: expected %s, got %v", errUnexpectedToken, delim, token)
+	}
+
+	return true, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("%w: expected %s, got %v", errUnexpectedToken, delim, token)
+	}
+
+	return nil
+}
+
+// skipValue reads the next value token by token, nested objects and arrays included.
+func skipValue(decoder *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func newProduct(request model.ProductsRequest, item responseProduct) model.Product {
+	colors := make([]string, 0, len(item.Colors))
+	for _, color := range item.Colors {
+		colors = append(colors, color.Name)
+	}
+
+	product := model.Product{
+		ExternalID:  item.ID,
+		Marketplace: model.MarketplaceWildberries,
+		CategoryID:  request.CategoryID,
+		RegionID:    request.RegionID,
+		Name:        item.Name,
+		Rating:      item.Rating,
+		URL:         fmt.Sprintf(request.ProductURL, item.ID),
+
+		Brand:   item.Brand,
+		BrandID: item.BrandID,
+
+		Colors: colors,
+		Sizes:  make([]model.ProductSize, 0, len(item.Sizes)),
+	}
+
+	for _, size := range item.Sizes {
+		var quantity uint32
+		for _, stock := range size.Stocks {
+			quantity += stock.Quantity
 		}
 
-		result = append(result, product)
+		product.Sizes = append(product.Sizes, model.ProductSize{
+			Name:           size.Name,
+			CurrentPrice:   size.Price.Total,
+			BasicPrice:     size.Price.Basic,
+			ProductPrice:   size.Price.Product,
+			LogisticsPrice: size.Price.Logistics,
+			ReturnFee:      size.Price.Return,
+			Quantity:       quantity,
+		})
 	}
 
-	return result, nil
+	return product
+}
+
+// requestURL formats the source url with the page, catalog urls carry their shard already.
+func requestURL(request model.ProductsRequest) string {
+	return fmt.Sprintf(request.RequestURL, request.Page)
 }
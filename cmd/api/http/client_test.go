@@ -0,0 +1,396 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/fakewb"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	httppkg "github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const testCategory = "dresses"
+
+func newTestProductClient(t *testing.T, retryCount uint) (*ProductClient, *fakewb.Server) {
+	t.Helper()
+
+	server := fakewb.NewServer()
+	t.Cleanup(server.Close)
+
+	if err := server.LoadFixture(testCategory, 1, "dresses-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewProductClient(log.NewNop(), ProductClientConfig{
+		RetryCount:    retryCount,
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: 10 * time.Millisecond,
+		RetryStatuses: []int{http.StatusTooManyRequests, http.StatusInternalServerError},
+	}, &http.Client{Timeout: 5 * time.Second})
+
+	return client, server
+}
+
+func testRequest(server *fakewb.Server, page int) model.ProductsRequest {
+	return model.ProductsRequest{
+		Page:       page,
+		Category:   testCategory,
+		CategoryID: 1,
+		RequestURL: server.RequestURL(testCategory),
+		ProductURL: server.ProductURL(),
+	}
+}
+
+type productStreamer interface {
+	StreamProducts(ctx context.Context, request model.ProductsRequest, yield func(product model.Product) error) error
+}
+
+func streamProducts(client productStreamer, request model.ProductsRequest) ([]model.Product, error) {
+	var products []model.Product
+	err := client.StreamProducts(context.Background(), request, func(product model.Product) error {
+		products = append(products, product)
+		return nil
+	})
+	return products, err
+}
+
+func TestProductClientStreamProducts(t *testing.T) {
+	client, server := newTestProductClient(t, 0)
+
+	products, err := streamProducts(client, testRequest(server, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(products) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(products))
+	}
+
+	product := products[0]
+	if product.ExternalID != 1001 || product.CategoryID != 1 || product.Brand != "Zarina" || product.BrandID != 10 {
+		t.Errorf("unexpected product: %+v", product)
+	}
+
+	if product.URL != "https://www.wildberries.ru/catalog/1001/detail.aspx" {
+		t.Errorf("unexpected product url: %s", product.URL)
+	}
+
+	if len(product.Colors) != 1 || product.Colors[0] != "черный" {
+		t.Errorf("unexpected colors: %v", product.Colors)
+	}
+
+	if len(product.Sizes) != 2 || product.Sizes[0].Name != "42" || product.Sizes[0].CurrentPrice != 350000 || product.Sizes[1].Quantity != 2 {
+		t.Errorf("unexpected sizes: %+v", product.Sizes)
+	}
+
+	if products[1].Sizes[0].CurrentPrice != 620000 {
+		t.Errorf("expected price 6200.00, got %v", products[1].Sizes[0].CurrentPrice)
+	}
+}
+
+func TestProductClientStreamProductsPriceChange(t *testing.T) {
+	client, server := newTestProductClient(t, 0)
+
+	if !server.SetPrice(testCategory, 1001, "42", 299000) {
+		t.Fatal("size not found in fixture")
+	}
+
+	products, err := streamProducts(client, testRequest(server, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if products[0].Sizes[0].CurrentPrice != 299000 || products[0].Sizes[1].CurrentPrice != 350000 {
+		t.Errorf("unexpected sizes: %+v", products[0].Sizes)
+	}
+}
+
+func TestProductClientStreamProductsSearch(t *testing.T) {
+	client, server := newTestProductClient(t, 0)
+
+	server.SetSearchPage("льняное платье", 1, []fakewb.Product{{
+		ID:    2001,
+		Name:  "Платье льняное",
+		Sizes: []fakewb.Size{{Name: "44", Price: fakewb.Price{Total: 410000}}},
+	}})
+
+	products, err := streamProducts(client, model.ProductsRequest{
+		Page:       1,
+		Type:       model.CategoryTypeSearch,
+		Category:   "q_test",
+		CategoryID: 2,
+		RequestURL: server.SearchURL(),
+		ProductURL: server.ProductURL(),
+		Query:      "льняное платье",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(products) != 1 || products[0].ExternalID != 2001 || products[0].CategoryID != 2 {
+		t.Fatalf("unexpected products: %+v", products)
+	}
+
+	if len(products[0].Sizes) != 1 || products[0].Sizes[0].CurrentPrice != 410000 {
+		t.Errorf("unexpected sizes: %+v", products[0].Sizes)
+	}
+}
+
+func TestProductClientStreamProductsBrand(t *testing.T) {
+	client, server := newTestProductClient(t, 0)
+
+	server.SetBrandPage(10, 2, []fakewb.Product{{ID: 3001, Brand: "Zarina", BrandID: 10}})
+
+	products, err := streamProducts(client, model.ProductsRequest{
+		Page:       2,
+		Type:       model.CategoryTypeBrand,
+		Category:   "b_10",
+		CategoryID: 3,
+		RequestURL: server.BrandURL(10),
+		ProductURL: server.ProductURL(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(products) != 1 || products[0].ExternalID != 3001 || products[0].BrandID != 10 || products[0].CategoryID != 3 {
+		t.Fatalf("unexpected products: %+v", products)
+	}
+}
+
+func TestProductClientStreamProductsResponses(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryCount uint
+		faults     []fakewb.Fault
+		page       int
+		products   int
+		requests   int
+		err        error
+		anyErr     bool
+	}{
+		{name: "empty page", page: 2, requests: 1},
+		{name: "null products", faults: []fakewb.Fault{fakewb.FaultNullProducts}, page: 1, requests: 1},
+		{name: "null data", faults: []fakewb.Fault{fakewb.FaultNullData}, page: 1, requests: 1},
+		{name: "null body", faults: []fakewb.Fault{fakewb.FaultNullBody}, page: 1, requests: 1},
+		{name: "no content", faults: []fakewb.Fault{fakewb.FaultNoContent}, page: 1, requests: 1},
+		{name: "too many requests", faults: []fakewb.Fault{fakewb.FaultTooManyRequests}, page: 1, requests: 1, err: model.ErrRequestLimit},
+		{
+			name:       "too many requests retried",
+			retryCount: 2,
+			faults:     []fakewb.Fault{fakewb.FaultTooManyRequests, fakewb.FaultInternalError},
+			page:       1,
+			products:   2,
+			requests:   3,
+		},
+		{name: "malformed json", faults: []fakewb.Fault{fakewb.FaultMalformedJSON}, page: 1, requests: 1, err: model.ErrMalformedPage},
+		{
+			name:     "unexpected format",
+			faults:   []fakewb.Fault{{Status: http.StatusOK, Body: `{"data":{"products":{"id":1001}}}`}},
+			page:     1,
+			requests: 1,
+			err:      model.ErrMalformedPage,
+		},
+		{name: "internal error", faults: []fakewb.Fault{fakewb.FaultInternalError}, page: 1, requests: 1, anyErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestProductClient(t, tt.retryCount)
+			server.Fail(testCategory, tt.page, tt.faults...)
+
+			products, err := streamProducts(client, testRequest(server, tt.page))
+
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+			case tt.anyErr:
+				if err == nil || errors.Is(err, model.ErrRequestLimit) || errors.Is(err, model.ErrMalformedPage) {
+					t.Fatalf("expected request error, got %v", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(products) != tt.products {
+				t.Errorf("expected %d products, got %d", tt.products, len(products))
+			}
+
+			if requests := server.Requests(testCategory, tt.page); requests != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
+
+func TestProductClientStreamProductsYieldError(t *testing.T) {
+	client, server := newTestProductClient(t, 0)
+	yieldErr := errors.New("save failed")
+
+	err := client.StreamProducts(context.Background(), testRequest(server, 1), func(_ model.Product) error {
+		return yieldErr
+	})
+
+	if !errors.Is(err, yieldErr) || errors.Is(err, model.ErrMalformedPage) {
+		t.Errorf("expected the yield error passed through, got %v", err)
+	}
+}
+
+func TestProductClientStreamProductsCassette(t *testing.T) {
+	cassette, err := httppkg.LoadCassette("testdata/cassettes/catalog.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewProductClient(log.NewNop(), ProductClientConfig{}, &http.Client{
+		Transport: httppkg.NewReplayTransport(cassette),
+	})
+
+	request := model.ProductsRequest{
+		Page:       1,
+		Category:   "women_clothes8",
+		CategoryID: 1,
+		RequestURL: fmt.Sprintf(catalogRequestURLFormat, "women_clothes8", 8137, -1257786),
+		ProductURL: catalogProductURL,
+	}
+
+	products, err := streamProducts(client, request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(products) != 1 {
+		t.Fatalf("expected 1 product, got %d", len(products))
+	}
+
+	product := products[0]
+	if product.ExternalID != 215430917 || product.Name != "Платье вечернее длинное" || product.Brand != "VIAVILLE" || product.BrandID != 311286 {
+		t.Errorf("unexpected product: %+v", product)
+	}
+
+	if product.Rating != 4.8 || len(product.Colors) != 1 || product.Colors[0] != "черный" {
+		t.Errorf("unexpected product details: %+v", product)
+	}
+
+	if len(product.Sizes) != 2 || product.Sizes[0].CurrentPrice != 314600 || product.Sizes[1].CurrentPrice != 329900 {
+		t.Errorf("unexpected sizes: %+v", product.Sizes)
+	}
+
+	if product.Sizes[0].Quantity != 14 || product.Sizes[1].Quantity != 3 {
+		t.Errorf("unexpected sizes: %+v", product.Sizes)
+	}
+
+	request.Page = 2
+	if products, err = streamProducts(client, request); err != nil || len(products) != 0 {
+		t.Errorf("expected empty last page, got %d products, error %v", len(products), err)
+	}
+}
+
+// benchmarkPage repeats the products of the recorded catalog page up to the size of a full page.
+func benchmarkPage(b *testing.B) []byte {
+	b.Helper()
+
+	cassette, err := httppkg.LoadCassette("testdata/cassettes/catalog.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var recorded struct {
+		Data struct {
+			Products []json.RawMessage `json:"products"`
+		} `json:"data"`
+	}
+	if err = json.Unmarshal(cassette.Interactions[0].Response.Body, &recorded); err != nil {
+		b.Fatal(err)
+	}
+
+	products := make([]json.RawMessage, 0, 100)
+	for len(products) < cap(products) {
+		products = append(products, recorded.Data.Products...)
+	}
+
+	var page struct {
+		State          int `json:"state"`
+		Version        int `json:"version"`
+		PayloadVersion int `json:"payloadVersion"`
+		Data           struct {
+			Products []json.RawMessage `json:"products"`
+		} `json:"data"`
+	}
+	page.Version, page.PayloadVersion = 2, 2
+	page.Data.Products = products[:cap(products)]
+
+	body, err := json.Marshal(page)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return body
+}
+
+// BenchmarkDecodeProductsBuffered is the decoding used before streaming, the whole page is decoded and then converted.
+func BenchmarkDecodeProductsBuffered(b *testing.B) {
+	body := benchmarkPage(b)
+	request := model.ProductsRequest{CategoryID: 1, ProductURL: catalogProductURL}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+
+	for b.Loop() {
+		var response struct {
+			Data struct {
+				Products []responseProduct `json:"products"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(&response); err != nil {
+			b.Fatal(err)
+		}
+
+		products := make([]model.Product, 0, len(response.Data.Products))
+		for _, item := range response.Data.Products {
+			products = append(products, newProduct(request, item))
+		}
+
+		if len(products) != 100 {
+			b.Fatalf("expected 100 products, got %d", len(products))
+		}
+	}
+}
+
+// BenchmarkDecodeProductsStreaming holds one chunk of products at a time like the crawler does.
+func BenchmarkDecodeProductsStreaming(b *testing.B) {
+	body := benchmarkPage(b)
+	request := model.ProductsRequest{CategoryID: 1, ProductURL: catalogProductURL}
+	chunk := make([]model.Product, 0, 50)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+
+	for b.Loop() {
+		var count int
+		if err := decodeProducts(bytes.NewReader(body), func(item responseProduct) error {
+			count++
+			chunk = append(chunk, newProduct(request, item))
+			if len(chunk) == cap(chunk) {
+				chunk = chunk[:0]
+			}
+			return nil
+		}); err != nil {
+			b.Fatal(err)
+		}
+
+		if count != 100 {
+			b.Fatalf("expected 100 products, got %d", count)
+		}
+		chunk = chunk[:0]
+	}
+}
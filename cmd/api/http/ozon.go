@@ -0,0 +1,238 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	httppkg "github.com/iamsorryprincess/wildberries-bot/internal/pkg/http"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	ozonProductURL          = "https://www.ozon.ru/product/%d/"
+	ozonNamePrefix          = "o_"
+	ozonSearchResultsWidget = "searchResultsV2"
+	// ozon listings have no size grid, every sku is a single offer
+	ozonSizeName = "0"
+)
+
+// category slugs end with the catalog id, e.g. /category/platya-zhenskie-7502/
+var ozonCategoryPath = regexp.MustCompile(`^/category/([a-z0-9-]+-(\d+))/?$`)
+
+type OzonClientConfig struct {
+	APIURL        string        `config:"api_url"`
+	RetryCount    uint          `config:"retry_count"`
+	RetryDelay    time.Duration `config:"retry_delay"`
+	MaxRetryDelay time.Duration `config:"max_retry_delay"`
+	RetryStatuses []int         `config:"retry_statuses"`
+}
+
+type OzonClient struct {
+	logger      log.Logger
+	config      OzonClientConfig
+	client      *http.Client
+	retryPolicy *httppkg.RetryPolicy
+}
+
+func NewOzonClient(logger log.Logger, config OzonClientConfig, httpClient *http.Client) *OzonClient {
+	return &OzonClient{
+		logger: logger,
+		config: config,
+		client: httpClient,
+		retryPolicy: httppkg.NewRetryPolicy(logger, httppkg.RetryConfig{
+			Count:    config.RetryCount,
+			Delay:    config.RetryDelay,
+			MaxDelay: config.MaxRetryDelay,
+			Statuses: config.RetryStatuses,
+		}),
+	}
+}
+
+type ozonItem struct {
+	SKU           uint64  `json:"sku"`
+	Name          string  `json:"name"`
+	Brand         string  `json:"brand"`
+	Rating        float32 `json:"rating"`
+	Size          string  `json:"size"`
+	Price         string  `json:"price"`
+	OriginalPrice string  `json:"originalPrice"`
+	Stock         uint32  `json:"stock"`
+}
+
+type ozonPage struct {
+	WidgetStates map[string]string `json:"widgetStates"`
+}
+
+// ResolveCategory describes the ozon category page as a category, the catalog id from the slug keeps names unique.
+func (c *OzonClient) ResolveCategory(_ context.Context, pageURL string) (model.Category, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return model.Category{}, fmt.Errorf("%w: url parse error: %w", model.ErrInvalidCategory, err)
+	}
+
+	match := ozonCategoryPath.FindStringSubmatch(parsedURL.Path)
+	if match == nil {
+		return model.Category{}, fmt.Errorf("%w: url is not an ozon category page", model.ErrInvalidCategory)
+	}
+
+	slug, catalogID := match[1], match[2]
+	title := strings.ReplaceAll(strings.TrimSuffix(slug, "-"+catalogID), "-", " ")
+
+	return model.Category{
+		Type:        model.CategoryTypeCatalog,
+		Marketplace: model.MarketplaceOzon,
+		Name:        ozonNamePrefix + catalogID,
+		Title:       sourceTitle(title),
+		RequestURL:  fmt.Sprintf("%s?url=/category/%s/?page=%%d", c.config.APIURL, slug),
+		ProductURL:  ozonProductURL,
+	}, nil
+}
+
+// StreamProducts requests one page and passes its products to yield, the items widget is a json string
+// so it is decoded as a whole and only the conversion is streamed.
+func (c *OzonClient) StreamProducts(ctx context.Context, request model.ProductsRequest, yield func(product model.Product) error) error {
+	ctx = httppkg.WithRouteKey(ctx, request.Category)
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(request.RequestURL, request.Page), nil)
+	if err != nil {
+		return fmt.Errorf("OzonClient.StreamProducts making http request error: %w", err)
+	}
+
+	httpRequest.Header.Add("Accept", "application/json")
+	httpRequest.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
+
+	httpResponse, err := c.retryPolicy.Do(c.client, httpRequest)
+	if err != nil {
+		if errors.Is(err, httppkg.ErrCircuitOpen) {
+			return model.ErrCircuitOpen
+		}
+		if errors.Is(err, httppkg.ErrNoProxyAvailable) {
+			return model.ErrRequestLimit
+		}
+		return fmt.Errorf("OzonClient.StreamProducts making http request error: %w", err)
+	}
+
+	defer func() {
+		if cErr := httpResponse.Body.Close(); cErr != nil {
+			c.logger.Warn().Err(cErr).Msg("OzonClient.StreamProducts failed to close response body")
+		}
+	}()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		if httpResponse.StatusCode == http.StatusTooManyRequests || httpResponse.StatusCode == http.StatusForbidden {
+			return model.ErrRequestLimit
+		}
+
+		body, rErr := io.ReadAll(httpResponse.Body)
+		if rErr != nil {
+			c.logger.Error().Err(rErr).Msg("OzonClient.StreamProducts failed to read response body")
+		}
+
+		c.logger.Error().Int("status", httpResponse.StatusCode).Str("body", string(body)).Send()
+		return fmt.Errorf("OzonClient.StreamProducts http status is not ok; status: %d", httpResponse.StatusCode)
+	}
+
+	var respData ozonPage
+	if err = json.NewDecoder(httpResponse.Body).Decode(&respData); err != nil {
+		if isFormatError(err) {
+			return fmt.Errorf("OzonClient.StreamProducts decode http request body error: %w: %w", model.ErrMalformedPage, err)
+		}
+		return fmt.Errorf("OzonClient.StreamProducts read http request body error: %w", err)
+	}
+
+	items, err := ozonSearchResults(respData)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		price, err := parseOzonPrice(item.Price)
+		if err != nil {
+			c.logger.Warn().Err(err).Uint64("sku", item.SKU).Msg("OzonClient.StreamProducts skipped item without price")
+			continue
+		}
+
+		basicPrice := price
+		if item.OriginalPrice != "" {
+			if basicPrice, err = parseOzonPrice(item.OriginalPrice); err != nil {
+				basicPrice = price
+			}
+		}
+
+		sizeName := item.Size
+		if sizeName == "" {
+			sizeName = ozonSizeName
+		}
+
+		if err = yield(model.Product{
+			ExternalID:  item.SKU,
+			Marketplace: model.MarketplaceOzon,
+			CategoryID:  request.CategoryID,
+			RegionID:    request.RegionID,
+			Name:        item.Name,
+			Rating:      item.Rating,
+			URL:         fmt.Sprintf(request.ProductURL, item.SKU),
+			Brand:       item.Brand,
+			Sizes: []model.ProductSize{{
+				Name:         sizeName,
+				CurrentPrice: price,
+				BasicPrice:   basicPrice,
+				ProductPrice: price,
+				Quantity:     item.Stock,
+			}},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ozonSearchResults decodes the search results widget, a page without it has no items.
+func ozonSearchResults(page ozonPage) ([]ozonItem, error) {
+	for key, state := range page.WidgetStates {
+		if !strings.HasPrefix(key, ozonSearchResultsWidget) {
+			continue
+		}
+
+		var results struct {
+			Items []ozonItem `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(state), &results); err != nil {
+			return nil, fmt.Errorf("OzonClient.StreamProducts decode widget %s error: %w: %w", key, model.ErrMalformedPage, err)
+		}
+
+		return results.Items, nil
+	}
+
+	return nil, nil
+}
+
+// parseOzonPrice parses prices formatted for display, e.g. "1 299 ₽" with thin spaces between thousands.
+// Listings round prices to rubles, kopecks after a comma are dropped.
+func parseOzonPrice(value string) (model.Money, error) {
+	value, _, _ = strings.Cut(value, ",")
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, value)
+
+	rubles, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse ozon price %q error: %w", value, err)
+	}
+
+	return model.Rubles(rubles), nil
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/fakeozon"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const testOzonCategory = "platya-zhenskie-7502"
+
+func newTestOzonClient(t *testing.T) (*OzonClient, *fakeozon.Server) {
+	t.Helper()
+
+	server := fakeozon.NewServer()
+	t.Cleanup(server.Close)
+
+	client := NewOzonClient(log.NewNop(), OzonClientConfig{
+		APIURL:        server.APIURL(),
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: 10 * time.Millisecond,
+	}, &http.Client{Timeout: 5 * time.Second})
+
+	return client, server
+}
+
+func TestOzonClientResolveCategory(t *testing.T) {
+	client, server := newTestOzonClient(t)
+
+	category, err := client.ResolveCategory(context.Background(), "https://www.ozon.ru/category/"+testOzonCategory+"/?sorting=score")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if category.Marketplace != model.MarketplaceOzon || category.Name != "o_7502" || category.Title != "platya zhenskie" {
+		t.Errorf("unexpected category: %+v", category)
+	}
+
+	if category.RequestURL != server.RequestURL(testOzonCategory) {
+		t.Errorf("unexpected request url: %s", category.RequestURL)
+	}
+
+	if _, err = client.ResolveCategory(context.Background(), "https://www.ozon.ru/product/123/"); !errors.Is(err, model.ErrInvalidCategory) {
+		t.Errorf("expected invalid category for a product page, got %v", err)
+	}
+}
+
+func TestOzonClientStreamProducts(t *testing.T) {
+	client, server := newTestOzonClient(t)
+
+	server.SetPage(testOzonCategory, 2, []fakeozon.Item{
+		{SKU: 501, Name: "Платье миди", Brand: "Zarina", Rating: 4.7, Price: "1 299 ₽", OriginalPrice: "2 599 ₽", Stock: 4},
+		{SKU: 502, Name: "Платье макси", Size: "44", Price: "3 100,90 ₽", Stock: 1},
+	})
+
+	products, err := streamProducts(client, model.ProductsRequest{
+		Page:        2,
+		Marketplace: model.MarketplaceOzon,
+		Category:    "o_7502",
+		CategoryID:  7,
+		RequestURL:  server.RequestURL(testOzonCategory),
+		ProductURL:  server.ProductURL(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if server.Requests(testOzonCategory, 2) != 1 {
+		t.Errorf("expected one request of page 2, got %d", server.Requests(testOzonCategory, 2))
+	}
+
+	if len(products) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(products))
+	}
+
+	product := products[0]
+	if product.ExternalID != 501 || product.Marketplace != model.MarketplaceOzon || product.CategoryID != 7 || product.URL != "https://www.ozon.ru/product/501/" {
+		t.Errorf("unexpected product: %+v", product)
+	}
+
+	if len(product.Sizes) != 1 || product.Sizes[0].Name != "0" || product.Sizes[0].CurrentPrice != model.Rubles(1299) ||
+		product.Sizes[0].BasicPrice != model.Rubles(2599) || product.Sizes[0].Quantity != 4 {
+		t.Errorf("unexpected sizes: %+v", product.Sizes)
+	}
+
+	if size := products[1].Sizes[0]; size.Name != "44" || size.CurrentPrice != model.Rubles(3100) || size.BasicPrice != size.CurrentPrice {
+		t.Errorf("unexpected size: %+v", size)
+	}
+
+	products, err = streamProducts(client, model.ProductsRequest{
+		Page:       3,
+		RequestURL: server.RequestURL(testOzonCategory),
+		ProductURL: server.ProductURL(),
+	})
+	if err != nil || len(products) != 0 {
+		t.Errorf("expected empty last page, got %d products and error %v", len(products), err)
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+import "errors"
+
+var (
+	ErrBrandNotFound  = errors.New("brand not found")
+	ErrSellerNotFound = errors.New("seller not found")
+)
+
+type Brand struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type Seller struct {
+	ID   uint64 `json:"id"`
+	Name string `json:"name"`
+}
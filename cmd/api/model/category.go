@@ -1,10 +1,33 @@
 package model
 
+import "errors"
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category already exists")
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrInvalidQuery     = errors.New("invalid search query")
+	ErrCrawlInProgress  = errors.New("category crawl is already running")
+)
+
+// CategoryType is the source a category is crawled from: a catalog section, a search query, a brand or a seller.
+type CategoryType uint8
+
+const (
+	CategoryTypeCatalog CategoryType = iota
+	CategoryTypeSearch
+	CategoryTypeBrand
+	CategoryTypeSeller
+)
+
 type Category struct {
-	ID         uint64 `json:"id"`
-	Name       string `json:"name"`
-	Title      string `json:"title"`
-	Emoji      string `json:"emoji"`
-	RequestURL string `json:"requestUrl"`
-	ProductURL string `json:"productUrl"`
+	ID          uint64       `json:"id"`
+	Type        CategoryType `json:"type"`
+	Marketplace Marketplace  `json:"marketplace"`
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	Emoji       string       `json:"emoji"`
+	RequestURL  string       `json:"requestUrl"`
+	ProductURL  string       `json:"productUrl"`
+	Query       string       `json:"query"`
 }
@@ -0,0 +1,19 @@
+package model
+
+import "errors"
+
+var ErrCategoryNodeNotFound = errors.New("category tree node not found")
+
+// CategoryNode is an item of the WB catalog menu, root items have no parent.
+// Category is set when the node is enabled, nodes without a shard can't be crawled and only group children.
+type CategoryNode struct {
+	ID          uint64   `json:"id"`
+	ParentID    uint64   `json:"parentId"`
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Shard       string   `json:"shard"`
+	CatalogID   uint64   `json:"catalogId"`
+	Position    int      `json:"position"`
+	HasChildren bool     `json:"hasChildren"`
+	Category    Category `json:"category"`
+}
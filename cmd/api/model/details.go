@@ -0,0 +1,20 @@
+package model
+
+import "errors"
+
+var ErrProductCardNotFound = errors.New("product card not found")
+
+type SizeChartRow struct {
+	Size   string            `json:"size"`
+	Values map[string]string `json:"values"`
+}
+
+type ProductDetails struct {
+	ProductID   uint64         `json:"productId"`
+	Description string         `json:"description"`
+	Composition string         `json:"composition"`
+	SellerID    uint64         `json:"sellerId"`
+	SellerName  string         `json:"sellerName"`
+	ImagesCount int            `json:"imagesCount"`
+	SizeChart   []SizeChartRow `json:"sizeChart"`
+}
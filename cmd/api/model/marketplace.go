@@ -0,0 +1,18 @@
+package model
+
+import "errors"
+
+var ErrUnknownMarketplace = errors.New("unknown marketplace")
+
+// Marketplace is the shop a category is crawled from, products keep the marketplace of the category they were found in.
+type Marketplace string
+
+const (
+	MarketplaceWildberries Marketplace = "wb"
+	MarketplaceOzon        Marketplace = "ozon"
+)
+
+// HasRegionPrices tells whether the prices depend on the delivery region, other marketplaces are crawled in the default region.
+func (m Marketplace) HasRegionPrices() bool {
+	return m == MarketplaceWildberries
+}
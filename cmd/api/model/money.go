@@ -0,0 +1,24 @@
+package model
+
+import "fmt"
+
+// Money is an amount in kopecks.
+type Money int64
+
+func Rubles(value uint64) Money {
+	return Money(value * 100)
+}
+
+func (m Money) Rubles() int64 {
+	return int64(m / 100)
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign = "-"
+		m = -m
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, int64(m/100), int64(m%100))
+}
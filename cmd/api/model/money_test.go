@@ -0,0 +1,20 @@
+package model
+
+import "testing"
+
+func TestMoneyString(t *testing.T) {
+	cases := map[Money]string{
+		0:         "0.00",
+		5:         "0.05",
+		299000:    "2990.00",
+		314650:    "3146.50",
+		-12345:    "-123.45",
+		Rubles(7): "7.00",
+	}
+
+	for money, expected := range cases {
+		if actual := money.String(); actual != expected {
+			t.Errorf("expected %q for %d kopecks, got %q", expected, int64(money), actual)
+		}
+	}
+}
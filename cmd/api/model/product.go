@@ -2,29 +2,48 @@ package model
 
 import "errors"
 
-var ErrRequestLimit = errors.New("request limit exceeded")
+var (
+	ErrRequestLimit  = errors.New("request limit exceeded")
+	ErrCircuitOpen   = errors.New("catalog requests are suspended")
+	ErrMalformedPage = errors.New("malformed products page")
+)
 
 type ProductsRequest struct {
-	Page       int    `json:"page"`
-	Category   string `json:"category"`
-	CategoryID uint64 `json:"category_id"`
-	RequestURL string `json:"requestUrl"`
-	ProductURL string `json:"productUrl"`
+	Page        int          `json:"page"`
+	Type        CategoryType `json:"type"`
+	Marketplace Marketplace  `json:"marketplace"`
+	Category    string       `json:"category"`
+	CategoryID  uint64       `json:"category_id"`
+	RegionID    uint64       `json:"regionId"`
+	Dest        int64        `json:"dest"`
+	RequestURL  string       `json:"requestUrl"`
+	ProductURL  string       `json:"productUrl"`
+	Query       string       `json:"query"`
 }
 
 type ProductSize struct {
-	Name          string  `json:"name"`
-	FirstPrice    float32 `json:"firstPrice"`
-	PreviousPrice float32 `json:"previousPrice"`
-	CurrentPrice  float32 `json:"currentPrice"`
+	Name           string `json:"name"`
+	FirstPrice     Money  `json:"firstPrice"`
+	PreviousPrice  Money  `json:"previousPrice"`
+	CurrentPrice   Money  `json:"currentPrice"`
+	BasicPrice     Money  `json:"basicPrice"`
+	ProductPrice   Money  `json:"productPrice"`
+	LogisticsPrice Money  `json:"logisticsPrice"`
+	ReturnFee      Money  `json:"returnFee"`
+	Quantity       uint32 `json:"quantity"`
 }
 
+// Product is a product of a page, ID is set once the product is saved and ExternalID is its id on the marketplace,
+// the same external id can belong to products of different marketplaces.
 type Product struct {
-	ID         uint64  `json:"id"`
-	CategoryID uint64  `json:"category_id"`
-	Name       string  `json:"name"`
-	Rating     float32 `json:"rating"`
-	URL        string  `json:"url"`
+	ID          uint64      `json:"id"`
+	ExternalID  uint64      `json:"externalId"`
+	Marketplace Marketplace `json:"marketplace"`
+	CategoryID  uint64      `json:"category_id"`
+	RegionID    uint64      `json:"regionId"`
+	Name        string      `json:"name"`
+	Rating      float32     `json:"rating"`
+	URL         string      `json:"url"`
 
 	Brand   string `json:"brand"`
 	BrandID uint64 `json:"brandId"`
@@ -32,3 +51,9 @@ type Product struct {
 	Colors []string      `json:"colors"`
 	Sizes  []ProductSize `json:"sizes"`
 }
+
+// ProductRef is a saved product, ID is the key of the products table and ExternalID the id on its marketplace.
+type ProductRef struct {
+	ID         uint64 `json:"id"`
+	ExternalID uint64 `json:"externalId"`
+}
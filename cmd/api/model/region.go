@@ -0,0 +1,13 @@
+package model
+
+import "errors"
+
+var ErrRegionNotFound = errors.New("region not found")
+
+// Region is a delivery destination, prices and availability on WB are calculated for its dest value.
+type Region struct {
+	ID        uint64 `json:"id"`
+	Name      string `json:"name"`
+	Dest      int64  `json:"dest"`
+	IsDefault bool   `json:"isDefault"`
+}
@@ -0,0 +1,18 @@
+package model
+
+type CategoryCrawlStats struct {
+	TrackingsCount    uint `json:"trackingsCount"`
+	SizesCount        uint `json:"sizesCount"`
+	ChangedSizesCount uint `json:"changedSizesCount"`
+	ThrottleCount     uint `json:"throttleCount"`
+}
+
+// CrawlCheckpoint is where the next crawl of a category starts, region 0 means the first crawl region.
+// Passes counts the crawls that reached the last page of every region,
+// SkippedPages counts the malformed pages the running pass went on after.
+type CrawlCheckpoint struct {
+	RegionID     uint64 `json:"regionId"`
+	Page         int    `json:"page"`
+	Passes       uint   `json:"passes"`
+	SkippedPages uint   `json:"skippedPages"`
+}
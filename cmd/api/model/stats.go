@@ -0,0 +1,15 @@
+package model
+
+type CategoryStats struct {
+	CategoryTitle string `json:"categoryTitle"`
+	CategoryEmoji string `json:"categoryEmoji"`
+	ProductsCount uint   `json:"productsCount"`
+	LastCrawl     string `json:"lastCrawl"`
+}
+
+type Stats struct {
+	ChatsCount     uint            `json:"chatsCount"`
+	TrackingsCount uint            `json:"trackingsCount"`
+	ProductsCount  uint            `json:"productsCount"`
+	Categories     []CategoryStats `json:"categories"`
+}
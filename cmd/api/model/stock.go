@@ -0,0 +1,37 @@
+package model
+
+import "errors"
+
+var (
+	ErrProductNotFound  = errors.New("product not found")
+	ErrAmbiguousProduct = errors.New("product id is found on several marketplaces")
+)
+
+type StockSubscription struct {
+	ChatID    int64  `json:"chatId"`
+	ProductID uint64 `json:"productId"`
+	SizeID    uint64 `json:"sizeId"`
+}
+
+type StockResult struct {
+	ChatID       int64
+	ProductID    uint64
+	ProductName  string
+	ProductURL   string
+	SizeID       uint64
+	Size         string
+	CurrentPrice Money
+}
+
+type ProductSizeStock struct {
+	SizeID      uint64 `json:"sizeId"`
+	Size        string `json:"size"`
+	IsAvailable bool   `json:"isAvailable"`
+}
+
+type ProductStock struct {
+	ProductID   uint64             `json:"productId"`
+	ProductName string             `json:"productName"`
+	ProductURL  string             `json:"productUrl"`
+	Sizes       []ProductSizeStock `json:"sizes"`
+}
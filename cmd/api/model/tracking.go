@@ -1,38 +1,96 @@
 package model
 
+type TrackingType uint8
+
+const (
+	TrackingTypePriceDrop TrackingType = iota
+	TrackingTypeNewArrivals
+	TrackingTypeLowStock
+)
+
+// PriceType selects the price a price drop tracking compares: the total price paid by the buyer
+// or the seller's product price before logistics.
+type PriceType uint8
+
+const (
+	PriceTypeTotal PriceType = iota
+	PriceTypeProduct
+)
+
 type TrackingSettings struct {
-	ChatID     int64  `json:"chatId"`
-	SizeID     uint64 `json:"sizeId"`
-	CategoryID uint64 `json:"categoryId"`
-	DiffValue  int    `json:"diffValue"`
+	ChatID         int64        `json:"chatId"`
+	SizeID         uint64       `json:"sizeId"`
+	CategoryID     uint64       `json:"categoryId"`
+	Type           TrackingType `json:"type"`
+	DiffValue      int          `json:"diffValue"`
+	PriceType      PriceType    `json:"priceType"`
+	MaxPrice       Money        `json:"maxPrice"`
+	StockThreshold uint32       `json:"stockThreshold"`
 }
 
 type TrackingResult struct {
-	ChatID          int64
-	ProductID       uint64
-	ProductName     string
-	ProductURL      string
-	SizeID          uint64
-	Size            string
-	PreviousPrice   float32
-	CurrentPrice    float32
-	CurrentPriceInt uint64
-	DiffPercent     int
+	ChatID         int64
+	ProductID      uint64
+	ProductName    string
+	ProductURL     string
+	SizeID         uint64
+	Size           string
+	PreviousPrice  Money
+	CurrentPrice   Money
+	BasicPrice     Money
+	LogisticsPrice Money
+	PriceType      PriceType
+	DiffPercent    int
 }
 
 type TrackingLog struct {
 	ChatID    int64
 	SizeID    uint64
 	ProductID uint64
-	Price     uint64
+	Price     Money
 }
 
 type TrackingSettingsInfo struct {
-	ChatID        int64  `json:"chatId"`
-	CategoryID    uint64 `json:"categoryId"`
-	CategoryTitle string `json:"categoryTitle"`
-	CategoryEmoji string `json:"categoryEmoji"`
-	SizeID        uint64 `json:"sizeId"`
-	Size          string `json:"size"`
-	DiffPercent   int    `json:"diffPercent"`
+	ChatID         int64        `json:"chatId"`
+	CategoryID     uint64       `json:"categoryId"`
+	CategoryTitle  string       `json:"categoryTitle"`
+	CategoryEmoji  string       `json:"categoryEmoji"`
+	SizeID         uint64       `json:"sizeId"`
+	Size           string       `json:"size"`
+	Type           TrackingType `json:"type"`
+	DiffPercent    int          `json:"diffPercent"`
+	PriceType      PriceType    `json:"priceType"`
+	MaxPrice       Money        `json:"maxPrice"`
+	StockThreshold uint32       `json:"stockThreshold"`
+}
+
+type NewArrivalResult struct {
+	ChatID        int64
+	CategoryTitle string
+	CategoryEmoji string
+	ProductID     uint64
+	ProductName   string
+	ProductURL    string
+	Size          string
+	CurrentPrice  Money
+}
+
+type NewArrivalsMessage struct {
+	ChatID        int64
+	CategoryTitle string
+	CategoryEmoji string
+	Items         []NewArrivalResult
+}
+
+type LowStockResult struct {
+	ChatID        int64
+	CategoryTitle string
+	CategoryEmoji string
+	ProductID     uint64
+	ProductName   string
+	ProductURL    string
+	SizeID        uint64
+	Size          string
+	CurrentPrice  Money
+	Quantity      uint32
 }
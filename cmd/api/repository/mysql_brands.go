@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+// MysqlBrandRepository looks brands and sellers up among the crawled products, WB has no lookup by name.
+type MysqlBrandRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlBrandRepository(conn *mysql.Connection) *MysqlBrandRepository {
+	return &MysqlBrandRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlBrandRepository) GetBrand(ctx context.Context, id uint64) (model.Brand, error) {
+	const query = "select brand_id, brand from products where brand_id = ? limit 1;"
+
+	var brand model.Brand
+	if err := r.conn.QueryRowContext(ctx, query, id).Scan(&brand.ID, &brand.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return brand, model.ErrBrandNotFound
+		}
+		return brand, fmt.Errorf("mysql get brand error: %w", err)
+	}
+
+	return brand, nil
+}
+
+func (r *MysqlBrandRepository) FindBrand(ctx context.Context, name string) (model.Brand, error) {
+	const query = "select brand_id, brand from products where brand = ? and brand_id > 0 limit 1;"
+
+	var brand model.Brand
+	if err := r.conn.QueryRowContext(ctx, query, name).Scan(&brand.ID, &brand.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return brand, model.ErrBrandNotFound
+		}
+		return brand, fmt.Errorf("mysql find brand error: %w", err)
+	}
+
+	return brand, nil
+}
+
+func (r *MysqlBrandRepository) GetSeller(ctx context.Context, id uint64) (model.Seller, error) {
+	const query = "select seller_id, seller_name from product_details where seller_id = ? limit 1;"
+
+	var seller model.Seller
+	if err := r.conn.QueryRowContext(ctx, query, id).Scan(&seller.ID, &seller.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return seller, model.ErrSellerNotFound
+		}
+		return seller, fmt.Errorf("mysql get seller error: %w", err)
+	}
+
+	return seller, nil
+}
+
+func (r *MysqlBrandRepository) FindSeller(ctx context.Context, name string) (model.Seller, error) {
+	const query = "select seller_id, seller_name from product_details where seller_name = ? and seller_id > 0 limit 1;"
+
+	var seller model.Seller
+	if err := r.conn.QueryRowContext(ctx, query, name).Scan(&seller.ID, &seller.Name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return seller, model.ErrSellerNotFound
+		}
+		return seller, fmt.Errorf("mysql find seller error: %w", err)
+	}
+
+	return seller, nil
+}
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
@@ -21,55 +24,319 @@ func NewMysqlCategoryRepository(logger log.Logger, conn *mysql.Connection) *Mysq
 	}
 }
 
-func (r *MysqlCategoryRepository) GetCategories(ctx context.Context) ([]model.Category, error) {
-	const query = "select id, name, title, emoji, request_url, product_url from categories;"
+// GetCategoryMenu returns the children of the node that lead to enabled catalog categories, parent 0 is the root.
+// An enabled parent is listed first as a leaf so it can be picked as a whole,
+// catalog categories not linked to the tree are listed in the root after the menu items.
+func (r *MysqlCategoryRepository) GetCategoryMenu(ctx context.Context, parentID uint64) ([]model.CategoryNode, error) {
+	const query = `with recursive enabled_nodes (id, parent_id) as (
+  select t.id, t.parent_id
+  from category_tree as t
+  join categories as c on c.id = t.category_id
+  where c.type = ?
+  union
+  select t.id, t.parent_id
+  from category_tree as t
+  join enabled_nodes as e on e.parent_id = t.id
+)
+select
+  t.id,
+  t.parent_id,
+  t.name,
+  exists (select 1 from enabled_nodes as ch where ch.parent_id = t.id),
+  coalesce(c.id, 0),
+  coalesce(c.title, ''),
+  coalesce(c.emoji, '')
+from
+  category_tree as t
+  join enabled_nodes as e on e.id = t.id
+  left join categories as c on c.id = t.category_id
+where
+  t.parent_id = ?
+order by
+  t.position;`
+
+	const parentQuery = `select
+  t.id,
+  t.parent_id,
+  t.name,
+  c.id,
+  c.title,
+  c.emoji
+from
+  category_tree as t
+  join categories as c on c.id = t.category_id
+where
+  t.id = ? and
+  c.type = ?;`
 
-	rows, err := r.conn.QueryContext(ctx, query)
+	const unlinkedQuery = `select
+  c.id,
+  c.title,
+  c.emoji
+from
+  categories as c
+where
+  c.type = ? and
+  not exists (select 1 from category_tree as t where t.category_id = c.id)
+order by
+  c.id;`
+
+	var result []model.CategoryNode
+	if parentID > 0 {
+		var parent model.CategoryNode
+		err := r.conn.QueryRowContext(ctx, parentQuery, parentID, model.CategoryTypeCatalog).Scan(
+			&parent.ID,
+			&parent.ParentID,
+			&parent.Name,
+			&parent.Category.ID,
+			&parent.Category.Title,
+			&parent.Category.Emoji,
+		)
+		if err == nil {
+			result = append(result, parent)
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("mysql get category menu parent error: %w", err)
+		}
+	}
+
+	rows, err := r.conn.QueryContext(ctx, query, model.CategoryTypeCatalog, parentID)
 	if err != nil {
-		return nil, fmt.Errorf("mysql get categories error: %w", err)
+		return nil, fmt.Errorf("mysql get category menu error: %w", err)
 	}
 
 	defer r.conn.CloseRows(rows)
 
-	var categories []model.Category
 	for rows.Next() {
-		var category model.Category
+		var node model.CategoryNode
 
 		if err = rows.Scan(
-			&category.ID,
-			&category.Name,
-			&category.Title,
-			&category.Emoji,
-			&category.RequestURL,
-			&category.ProductURL,
+			&node.ID,
+			&node.ParentID,
+			&node.Name,
+			&node.HasChildren,
+			&node.Category.ID,
+			&node.Category.Title,
+			&node.Category.Emoji,
 		); err != nil {
-			return nil, fmt.Errorf("mysql scan categories row error: %w", err)
+			return nil, fmt.Errorf("mysql scan category menu row error: %w", err)
 		}
 
-		categories = append(categories, category)
+		result = append(result, node)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("mysql categories row error: %w", err)
+		return nil, fmt.Errorf("mysql category menu rows error: %w", err)
 	}
 
-	return categories, nil
+	if parentID > 0 {
+		return result, nil
+	}
+
+	unlinkedRows, err := r.conn.QueryContext(ctx, unlinkedQuery, model.CategoryTypeCatalog)
+	if err != nil {
+		return nil, fmt.Errorf("mysql get unlinked categories error: %w", err)
+	}
+
+	defer r.conn.CloseRows(unlinkedRows)
+
+	for unlinkedRows.Next() {
+		var node model.CategoryNode
+
+		if err = unlinkedRows.Scan(&node.Category.ID, &node.Category.Title, &node.Category.Emoji); err != nil {
+			return nil, fmt.Errorf("mysql scan unlinked categories row error: %w", err)
+		}
+
+		node.Name = node.Category.Title
+		result = append(result, node)
+	}
+
+	if err = unlinkedRows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql unlinked categories rows error: %w", err)
+	}
+
+	return result, nil
 }
 
 func (r *MysqlCategoryRepository) GetCategory(ctx context.Context, id uint64) (model.Category, error) {
-	const query = "select id, name, title, emoji, request_url, product_url from categories where id = ?;"
+	const query = "select id, type, marketplace, name, title, emoji, request_url, product_url, coalesce(query, '') from categories where id = ?;"
 
 	var category model.Category
 	if err := r.conn.QueryRowContext(ctx, query, id).Scan(
 		&category.ID,
+		&category.Type,
+		&category.Marketplace,
 		&category.Name,
 		&category.Title,
 		&category.Emoji,
 		&category.RequestURL,
 		&category.ProductURL,
+		&category.Query,
 	); err != nil {
 		return category, fmt.Errorf("mysql get category error: %w", err)
 	}
 
 	return category, nil
 }
+
+func (r *MysqlCategoryRepository) GetCategoryByName(ctx context.Context, name string) (model.Category, error) {
+	const query = "select id, type, marketplace, name, title, emoji, request_url, product_url, coalesce(query, '') from categories where name = ?;"
+
+	var category model.Category
+	if err := r.conn.QueryRowContext(ctx, query, name).Scan(
+		&category.ID,
+		&category.Type,
+		&category.Marketplace,
+		&category.Name,
+		&category.Title,
+		&category.Emoji,
+		&category.RequestURL,
+		&category.ProductURL,
+		&category.Query,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return category, model.ErrCategoryNotFound
+		}
+		return category, fmt.Errorf("mysql get category by name error: %w", err)
+	}
+
+	return category, nil
+}
+
+func (r *MysqlCategoryRepository) AddCategory(ctx context.Context, category model.Category) (uint64, error) {
+	const query = "insert into categories (type, marketplace, name, title, emoji, request_url, product_url, query) values (?, ?, ?, ?, ?, ?, ?, nullif(?, ''));"
+
+	result, err := r.conn.ExecContext(ctx, query, category.Type, category.Marketplace, category.Name, category.Title, category.Emoji, category.RequestURL, category.ProductURL, category.Query)
+	if err != nil {
+		return 0, fmt.Errorf("mysql insert categories error: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("mysql insert categories last insert id error: %w", err)
+	}
+
+	return uint64(id), nil
+}
+
+func (r *MysqlCategoryRepository) GetCategoriesToCrawl(ctx context.Context, crawlIdle bool) ([]model.Category, error) {
+	const query = `select c.id, c.type, c.marketplace, c.name, c.title, c.emoji, c.request_url, c.product_url, coalesce(c.query, '')
+from categories as c
+where
+  c.next_crawl_at is null or
+  (
+    c.next_crawl_at <= NOW() and
+    (
+      (? and c.type = ?) or
+      exists (select 1 from tracking_settings as ts where ts.category_id = c.id) or
+      exists (select 1 from stock_subscriptions as ss join categories_products as cp on cp.product_id = ss.product_id where cp.category_id = c.id)
+    )
+  )
+order by c.next_crawl_at;`
+
+	rows, err := r.conn.QueryContext(ctx, query, crawlIdle, model.CategoryTypeCatalog)
+	if err != nil {
+		return nil, fmt.Errorf("mysql get categories to crawl error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var categories []model.Category
+	for rows.Next() {
+		var category model.Category
+
+		if err = rows.Scan(
+			&category.ID,
+			&category.Type,
+			&category.Marketplace,
+			&category.Name,
+			&category.Title,
+			&category.Emoji,
+			&category.RequestURL,
+			&category.ProductURL,
+			&category.Query,
+		); err != nil {
+			return nil, fmt.Errorf("mysql scan categories to crawl row error: %w", err)
+		}
+
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql categories to crawl row error: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (r *MysqlCategoryRepository) GetCrawlStats(ctx context.Context, id uint64, crawlDuration time.Duration) (model.CategoryCrawlStats, error) {
+	const query = `select
+  (select count(*) from tracking_settings where category_id = ?) +
+  (select count(*) from stock_subscriptions as ss join categories_products as cp on cp.product_id = ss.product_id where cp.category_id = ?),
+  count(ps.product_id),
+  coalesce(sum(ps.price_changed_at >= NOW() - INTERVAL ? SECOND), 0),
+  (select throttle_count from categories where id = ?)
+from
+  products_sizes as ps
+  join categories_products as cp on cp.product_id = ps.product_id
+where
+  cp.category_id = ? and
+  ps.updated_at >= NOW() - INTERVAL ? SECOND;`
+
+	var stats model.CategoryCrawlStats
+	seconds := int64(crawlDuration.Seconds()) + 1
+	if err := r.conn.QueryRowContext(ctx, query, id, id, seconds, id, id, seconds).Scan(
+		&stats.TrackingsCount,
+		&stats.SizesCount,
+		&stats.ChangedSizesCount,
+		&stats.ThrottleCount,
+	); err != nil {
+		return stats, fmt.Errorf("mysql get category crawl stats error: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (r *MysqlCategoryRepository) UpdateCrawlSchedule(ctx context.Context, id uint64, nextInterval time.Duration, throttleCount uint) error {
+	const query = `update categories
+set
+  crawled_at = NOW(),
+  next_crawl_at = NOW() + INTERVAL ? SECOND,
+  throttle_count = ?
+where id = ?`
+
+	if _, err := r.conn.ExecContext(ctx, query, int64(nextInterval.Seconds()), throttleCount, id); err != nil {
+		return fmt.Errorf("mysql update categories crawl schedule error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MysqlCategoryRepository) GetCrawlCheckpoint(ctx context.Context, id uint64) (model.CrawlCheckpoint, error) {
+	const query = "select crawl_region_id, crawl_page, crawl_passes, crawl_skipped_pages from categories where id = ?;"
+
+	var checkpoint model.CrawlCheckpoint
+	if err := r.conn.QueryRowContext(ctx, query, id).Scan(&checkpoint.RegionID, &checkpoint.Page, &checkpoint.Passes, &checkpoint.SkippedPages); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return checkpoint, model.ErrCategoryNotFound
+		}
+		return checkpoint, fmt.Errorf("mysql get category crawl checkpoint error: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+func (r *MysqlCategoryRepository) SaveCrawlCheckpoint(ctx context.Context, id uint64, checkpoint model.CrawlCheckpoint) error {
+	const query = "update categories set crawl_region_id = ?, crawl_page = ?, crawl_passes = ?, crawl_skipped_pages = ? where id = ?;"
+	if _, err := r.conn.ExecContext(ctx, query, checkpoint.RegionID, checkpoint.Page, checkpoint.Passes, checkpoint.SkippedPages, id); err != nil {
+		return fmt.Errorf("mysql save category crawl checkpoint error: %w", err)
+	}
+	return nil
+}
+
+func (r *MysqlCategoryRepository) StartCrawlPass(ctx context.Context, id uint64) error {
+	const query = "update categories set crawl_pass_started_at = NOW() where id = ?;"
+	if _, err := r.conn.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("mysql start category crawl pass error: %w", err)
+	}
+	return nil
+}
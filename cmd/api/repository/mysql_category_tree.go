@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+// the WB menu has a few thousand items, they are upserted in chunks to keep statements small
+const categoryTreeChunkSize = 500
+
+type MysqlCategoryTreeRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlCategoryTreeRepository(conn *mysql.Connection) *MysqlCategoryTreeRepository {
+	return &MysqlCategoryTreeRepository{
+		conn: conn,
+	}
+}
+
+// SaveTree upserts the synced nodes and deletes the ones that disappeared from the menu.
+// Enabled categories stay linked to their nodes, categories of deleted nodes are kept and only lose the link.
+func (r *MysqlCategoryTreeRepository) SaveTree(ctx context.Context, nodes []model.CategoryNode, syncedAt time.Time) (int64, error) {
+	const insertSQL = `insert into
+  category_tree (id, parent_id, name, url, shard, catalog_id, position, synced_at)
+values `
+	const valuesStmt = "(?, ?, ?, ?, ?, ?, ?, ?)"
+	const updateSQL = ` as new_values on duplicate key update
+  parent_id = new_values.parent_id,
+  name = new_values.name,
+  url = new_values.url,
+  shard = new_values.shard,
+  catalog_id = new_values.catalog_id,
+  position = new_values.position,
+  synced_at = new_values.synced_at;`
+	const deleteQuery = "delete from category_tree where synced_at < ?;"
+
+	for start := 0; start < len(nodes); start += categoryTreeChunkSize {
+		chunk := nodes[start:min(start+categoryTreeChunkSize, len(nodes))]
+
+		var builder strings.Builder
+		builder.WriteString(insertSQL)
+		args := make([]interface{}, 0, len(chunk)*8)
+
+		for i, node := range chunk {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			builder.WriteString(valuesStmt)
+			args = append(args, node.ID, node.ParentID, node.Name, node.URL, node.Shard, node.CatalogID, node.Position, syncedAt)
+		}
+
+		builder.WriteString(updateSQL)
+
+		if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+			return 0, fmt.Errorf("mysql upsert category_tree error: %w", err)
+		}
+	}
+
+	result, err := r.conn.ExecContext(ctx, deleteQuery, syncedAt)
+	if err != nil {
+		return 0, fmt.Errorf("mysql delete stale category_tree error: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("mysql delete stale category_tree rows affected error: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// LinkCategories links wildberries catalog categories added by url to their nodes by the cat id of the request url,
+// names are not matched as legacy categories are named apart from the shard.
+// A category already linked to another node is skipped by the unique key.
+func (r *MysqlCategoryTreeRepository) LinkCategories(ctx context.Context) error {
+	const query = `update ignore
+  category_tree as t
+  join categories as c on c.request_url like concat('%?cat=', t.catalog_id, '&%') or c.request_url like concat('%&cat=', t.catalog_id, '&%')
+set
+  t.category_id = c.id
+where
+  t.category_id is null and
+  t.catalog_id > 0 and
+  c.type = ? and
+  c.marketplace = ?;`
+
+	if _, err := r.conn.ExecContext(ctx, query, model.CategoryTypeCatalog, model.MarketplaceWildberries); err != nil {
+		return fmt.Errorf("mysql link category_tree categories error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MysqlCategoryTreeRepository) LinkCategory(ctx context.Context, nodeID uint64, categoryID uint64) error {
+	const query = "update category_tree set category_id = ? where id = ?;"
+	if _, err := r.conn.ExecContext(ctx, query, categoryID, nodeID); err != nil {
+		return fmt.Errorf("mysql link category_tree node error: %w", err)
+	}
+	return nil
+}
+
+func (r *MysqlCategoryTreeRepository) GetNode(ctx context.Context, id uint64) (model.CategoryNode, error) {
+	const query = `select
+  t.id,
+  t.parent_id,
+  t.name,
+  t.url,
+  t.shard,
+  t.catalog_id,
+  t.position,
+  exists (select 1 from category_tree as ch where ch.parent_id = t.id),
+  coalesce(c.id, 0),
+  coalesce(c.title, ''),
+  coalesce(c.emoji, '')
+from
+  category_tree as t
+  left join categories as c on c.id = t.category_id
+where
+  t.id = ?;`
+
+	var node model.CategoryNode
+	if err := r.conn.QueryRowContext(ctx, query, id).Scan(
+		&node.ID,
+		&node.ParentID,
+		&node.Name,
+		&node.URL,
+		&node.Shard,
+		&node.CatalogID,
+		&node.Position,
+		&node.HasChildren,
+		&node.Category.ID,
+		&node.Category.Title,
+		&node.Category.Emoji,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return node, model.ErrCategoryNodeNotFound
+		}
+		return node, fmt.Errorf("mysql get category_tree node error: %w", err)
+	}
+
+	return node, nil
+}
+
+// GetChildren returns all children of the node, parent 0 is the root of the menu.
+func (r *MysqlCategoryTreeRepository) GetChildren(ctx context.Context, parentID uint64) ([]model.CategoryNode, error) {
+	const query = `select
+  t.id,
+  t.parent_id,
+  t.name,
+  t.url,
+  t.shard,
+  t.catalog_id,
+  t.position,
+  exists (select 1 from category_tree as ch where ch.parent_id = t.id),
+  coalesce(c.id, 0),
+  coalesce(c.title, ''),
+  coalesce(c.emoji, '')
+from
+  category_tree as t
+  left join categories as c on c.id = t.category_id
+where
+  t.parent_id = ?
+order by
+  t.position;`
+
+	rows, err := r.conn.QueryContext(ctx, query, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("mysql get category_tree children error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []model.CategoryNode
+	for rows.Next() {
+		var node model.CategoryNode
+
+		if err = rows.Scan(
+			&node.ID,
+			&node.ParentID,
+			&node.Name,
+			&node.URL,
+			&node.Shard,
+			&node.CatalogID,
+			&node.Position,
+			&node.HasChildren,
+			&node.Category.ID,
+			&node.Category.Title,
+			&node.Category.Emoji,
+		); err != nil {
+			return nil, fmt.Errorf("mysql scan category_tree children row error: %w", err)
+		}
+
+		result = append(result, node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql get category_tree children rows error: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+type MysqlChatRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlChatRepository(conn *mysql.Connection) *MysqlChatRepository {
+	return &MysqlChatRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlChatRepository) SaveChat(ctx context.Context, chatID int64) error {
+	const query = "insert into chats (chat_id) values (?) on duplicate key update updated_at = NOW();"
+	if _, err := r.conn.ExecContext(ctx, query, chatID); err != nil {
+		return fmt.Errorf("mysql insert chats error: %w", err)
+	}
+	return nil
+}
+
+func (r *MysqlChatRepository) DeleteChat(ctx context.Context, chatID int64) error {
+	const query = "delete from chats where chat_id = ?"
+	if _, err := r.conn.ExecContext(ctx, query, chatID); err != nil {
+		return fmt.Errorf("mysql delete from chats error: %w", err)
+	}
+	return nil
+}
+
+func (r *MysqlChatRepository) GetChatIDs(ctx context.Context) ([]int64, error) {
+	const query = "select chat_id from chats;"
+
+	rows, err := r.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("mysql get chats error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []int64
+	for rows.Next() {
+		var chatID int64
+
+		if err = rows.Scan(&chatID); err != nil {
+			return nil, fmt.Errorf("mysql scan chats row error: %w", err)
+		}
+
+		result = append(result, chatID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql get chats rows error: %w", err)
+	}
+
+	return result, nil
+}
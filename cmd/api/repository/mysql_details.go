@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+type MysqlProductDetailsRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlProductDetailsRepository(conn *mysql.Connection) *MysqlProductDetailsRepository {
+	return &MysqlProductDetailsRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlProductDetailsRepository) SaveDetails(ctx context.Context, details []model.ProductDetails) error {
+	const query = `insert into
+  product_details (
+    product_id,
+    description,
+    composition,
+    seller_id,
+    seller_name,
+    images_count,
+    size_chart
+  ) values `
+	const valuesStmt = "(?, ?, ?, ?, ?, ?, ?)"
+	const onDuplicate = ` as new_values on duplicate key update
+  description = new_values.description,
+  composition = new_values.composition,
+  seller_id = new_values.seller_id,
+  seller_name = new_values.seller_name,
+  images_count = new_values.images_count,
+  size_chart = new_values.size_chart,
+  updated_at = NOW();`
+
+	if len(details) == 0 {
+		return nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString(query)
+	args := make([]interface{}, 0, len(details)*7)
+
+	for i, item := range details {
+		var sizeChart interface{}
+		if len(item.SizeChart) > 0 {
+			data, err := json.Marshal(item.SizeChart)
+			if err != nil {
+				return fmt.Errorf("product details marshal size chart error: %w", err)
+			}
+			sizeChart = string(data)
+		}
+
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(valuesStmt)
+		args = append(args, item.ProductID, item.Description, item.Composition, item.SellerID, item.SellerName, item.ImagesCount, sizeChart)
+	}
+
+	builder.WriteString(onDuplicate)
+
+	if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+		return fmt.Errorf("mysql insert product_details error: %w", err)
+	}
+
+	return nil
+}
@@ -23,10 +23,13 @@ func NewMysqlProductRepository(logger log.Logger, conn *mysql.Connection) *Mysql
 	}
 }
 
-func (r *MysqlProductRepository) Update(ctx context.Context, products []model.Product) error {
+// Update saves one catalog page, all products of the page are expected to be crawled from the same marketplace for the same region.
+// Products are matched by their marketplace id, the products saved for the first time are returned.
+func (r *MysqlProductRepository) Update(ctx context.Context, products []model.Product) ([]model.ProductRef, error) {
 	const insertProductsSQL = `insert into
   products (
-    id,
+    external_id,
+    marketplace,
 	category_id,
     name,
     rating,
@@ -34,36 +37,40 @@ func (r *MysqlProductRepository) Update(ctx context.Context, products []model.Pr
     brand,
     brand_id,
     colors,
+    last_seen_at,
     created_at
   ) values `
-	const insertProductsValuesStmt = "(?, ?, ?, ?, ?, ?, ?, ?, NOW())"
+	const insertProductsValuesStmt = "(?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())"
 
 	const insertProductSizesSQL = `insert into
   products_sizes (
     product_id,
     size_id,
+    region_id,
     first_price,
-    previous_price,
     current_price,
-	current_price_int,
+    basic_price,
+    product_price,
+    logistics_price,
+    return_fee,
+    price_changed_at,
+    is_available,
+    quantity,
+    last_seen_at,
     created_at
   ) values `
-	const insertProductSizesValuesStmt = "(?, ?, ?, ?, ?, ?, NOW())"
+	// a size listed without stock is sold out, it is available again once it is restocked
+	const insertProductSizesValuesStmt = "(?, ?, ?, ?, ?, ?, ?, ?, ?, if(?, NOW(), NULL), ?, ?, NOW(), NOW())"
 
-	sizesMap, err := r.updateSizes(ctx, products)
+	existingIDs, err := r.getProductIDs(ctx, products)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var insertProductsBuilder strings.Builder
 	insertProductsBuilder.WriteString(insertProductsSQL)
-	productArgs := make([]interface{}, 0, len(products)*8)
-
-	var insertSizesBuilder strings.Builder
-	insertSizesBuilder.WriteString(insertProductSizesSQL)
-	var sizeArgs []interface{}
+	productArgs := make([]interface{}, 0, len(products)*9)
 
-	sizesIndex := 0
 	for i, product := range products {
 		if i > 0 {
 			insertProductsBuilder.WriteString(", ")
@@ -71,40 +78,286 @@ func (r *MysqlProductRepository) Update(ctx context.Context, products []model.Pr
 
 		colorsJSON, err := json.Marshal(product.Colors)
 		if err != nil {
-			r.logger.Warn().Err(err).Uint64("product_id", product.ID).Msg("failed marshal colors array")
+			r.logger.Warn().Err(err).Uint64("external_id", product.ExternalID).Msg("failed marshal colors array")
 			colorsJSON = []byte("[]")
 		}
 
 		insertProductsBuilder.WriteString(insertProductsValuesStmt)
-		productArgs = append(productArgs, product.ID, product.CategoryID, product.Name, product.Rating, product.URL, product.Brand, product.BrandID, string(colorsJSON))
+		productArgs = append(productArgs, product.ExternalID, product.Marketplace, product.CategoryID, product.Name, product.Rating, product.URL, product.Brand, product.BrandID, string(colorsJSON))
+	}
+
+	// a delisted product that shows up again is listed back
+	const duplicateProductsStmt = " on duplicate key update last_seen_at = NOW(), delisted_at = NULL, updated_at = NOW();"
+	insertProductsBuilder.WriteString(duplicateProductsStmt)
+
+	if _, err := r.conn.ExecContext(ctx, insertProductsBuilder.String(), productArgs...); err != nil {
+		return nil, fmt.Errorf("mysql products repository: failed exec insert products: %w", err)
+	}
+
+	savedIDs, err := r.getProductIDs(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rest of the page is saved under the ids of the products table, the products of the caller are left as they are
+	var newProducts []model.ProductRef
+	saved := make([]model.Product, 0, len(products))
+	for _, product := range products {
+		id, ok := savedIDs[product.ExternalID]
+		if !ok {
+			return nil, fmt.Errorf("mysql products repository: product %d of %s is not saved", product.ExternalID, product.Marketplace)
+		}
+
+		if _, ok = existingIDs[product.ExternalID]; !ok {
+			newProducts = append(newProducts, model.ProductRef{ID: id, ExternalID: product.ExternalID})
+		}
+
+		product.ID = id
+		saved = append(saved, product)
+	}
+	products = saved
 
+	sizesMap, err := r.updateSizes(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+
+	changedSizes, err := r.getChangedPriceSizes(ctx, products, sizesMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var insertSizesBuilder strings.Builder
+	insertSizesBuilder.WriteString(insertProductSizesSQL)
+	var sizeArgs []interface{}
+
+	sizesIndex := 0
+	for _, product := range products {
 		for _, size := range product.Sizes {
 			if sizesIndex > 0 {
 				insertSizesBuilder.WriteString(", ")
 			}
 
+			_, isPriceChanged := changedSizes[productSizeKey{productID: product.ID, sizeID: sizesMap[size.Name]}]
+
 			insertSizesBuilder.WriteString(insertProductSizesValuesStmt)
-			sizeArgs = append(sizeArgs, product.ID, sizesMap[size.Name], size.CurrentPrice, size.CurrentPrice, size.CurrentPrice, uint64(size.CurrentPrice))
+			sizeArgs = append(sizeArgs, product.ID, sizesMap[size.Name], product.RegionID, size.CurrentPrice, size.CurrentPrice,
+				size.BasicPrice, size.ProductPrice, size.LogisticsPrice, size.ReturnFee, isPriceChanged, size.Quantity > 0, size.Quantity)
 			sizesIndex++
 		}
 	}
 
-	const duplicateProductsStmt = " on duplicate key update updated_at = NOW();"
-	insertProductsBuilder.WriteString(duplicateProductsStmt)
-
-	if _, err := r.conn.ExecContext(ctx, insertProductsBuilder.String(), productArgs...); err != nil {
-		return fmt.Errorf("mysql products repository: failed exec insert products: %w", err)
+	if err = r.linkCategory(ctx, products); err != nil {
+		return nil, err
 	}
 
+	// price drops are matched against the prices each source saw last, see MysqlTrackingRepository.SavePriceBaselines.
+	// No assignment reads a column another one writes, they hold whatever order they are run in.
 	const duplicateSizesStmt = ` as new_values on duplicate key update
-  previous_price = products_sizes.current_price,
+  price_changed_at = coalesce(new_values.price_changed_at, products_sizes.price_changed_at),
   current_price = new_values.current_price,
-  current_price_int = new_values.current_price_int,
+  basic_price = new_values.basic_price,
+  product_price = new_values.product_price,
+  logistics_price = new_values.logistics_price,
+  return_fee = new_values.return_fee,
+  is_available = new_values.quantity > 0,
+  quantity = new_values.quantity,
+  last_seen_at = NOW(),
+  missed_passes = 0,
+  is_delisted = 0,
   updated_at = NOW();`
 	insertSizesBuilder.WriteString(duplicateSizesStmt)
 
-	if _, err := r.conn.ExecContext(ctx, insertSizesBuilder.String(), sizeArgs...); err != nil {
-		return fmt.Errorf("mysql products repository: failed exec insert product sizes: %w", err)
+	if sizesIndex > 0 {
+		if _, err := r.conn.ExecContext(ctx, insertSizesBuilder.String(), sizeArgs...); err != nil {
+			return nil, fmt.Errorf("mysql products repository: failed exec insert product sizes: %w", err)
+		}
+	}
+
+	if err = r.markUnavailableSizes(ctx, products, sizesMap); err != nil {
+		return nil, err
+	}
+
+	if err = r.saveStockHistory(ctx, products); err != nil {
+		return nil, err
+	}
+
+	return newProducts, nil
+}
+
+// getProductIDs maps the marketplace ids of the saved products to their ids in the products table.
+func (r *MysqlProductRepository) getProductIDs(ctx context.Context, products []model.Product) (map[uint64]uint64, error) {
+	const selectQuery = "select external_id, id from products where marketplace = ? and external_id in ("
+
+	var builder strings.Builder
+	builder.WriteString(selectQuery)
+	args := make([]interface{}, 0, len(products)+1)
+	args = append(args, products[0].Marketplace)
+
+	for i, product := range products {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("?")
+		args = append(args, product.ExternalID)
+	}
+
+	builder.WriteString(")")
+
+	rows, err := r.conn.QueryContext(ctx, builder.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("mysql products repository: failed select product ids: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	result := make(map[uint64]uint64, len(products))
+	for rows.Next() {
+		var externalID, id uint64
+
+		if err = rows.Scan(&externalID, &id); err != nil {
+			return nil, fmt.Errorf("mysql products repository: failed scan product ids row: %w", err)
+		}
+
+		result[externalID] = id
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql products repository: product ids rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+type productSizeKey struct {
+	productID uint64
+	sizeID    uint64
+}
+
+// getChangedPriceSizes returns the saved sizes of the region whose total or product price differs from the page.
+func (r *MysqlProductRepository) getChangedPriceSizes(
+	ctx context.Context,
+	products []model.Product,
+	sizesMap map[string]uint64,
+) (map[productSizeKey]struct{}, error) {
+	const selectQuery = "select product_id, size_id, current_price, product_price from products_sizes where region_id = ? and product_id in ("
+
+	prices := make(map[productSizeKey]model.ProductSize)
+
+	var builder strings.Builder
+	builder.WriteString(selectQuery)
+	args := make([]interface{}, 0, len(products)+1)
+	args = append(args, products[0].RegionID)
+
+	for i, product := range products {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("?")
+		args = append(args, product.ID)
+
+		for _, size := range product.Sizes {
+			prices[productSizeKey{productID: product.ID, sizeID: sizesMap[size.Name]}] = size
+		}
+	}
+
+	builder.WriteString(")")
+
+	rows, err := r.conn.QueryContext(ctx, builder.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("mysql products repository: failed select size prices: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	result := make(map[productSizeKey]struct{})
+	for rows.Next() {
+		var key productSizeKey
+		var currentPrice, productPrice model.Money
+
+		if err = rows.Scan(&key.productID, &key.sizeID, &currentPrice, &productPrice); err != nil {
+			return nil, fmt.Errorf("mysql products repository: failed scan size prices row: %w", err)
+		}
+
+		if size, ok := prices[key]; ok && (size.CurrentPrice != currentPrice || size.ProductPrice != productPrice) {
+			result[key] = struct{}{}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql products repository: size prices rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// linkCategory remembers that products were found in the crawled category, a product can be listed by several catalog sections and search queries.
+func (r *MysqlProductRepository) linkCategory(ctx context.Context, products []model.Product) error {
+	const insertQuery = "insert into categories_products (category_id, product_id, last_seen_at) values "
+	// misses are counted per source, the link seen again is reset
+	const duplicateStmt = " on duplicate key update last_seen_at = NOW(), missed_passes = 0;"
+
+	var builder strings.Builder
+	builder.WriteString(insertQuery)
+	args := make([]interface{}, 0, len(products)*2)
+
+	for i, product := range products {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("(?, ?, NOW())")
+		args = append(args, product.CategoryID, product.ID)
+	}
+
+	builder.WriteString(duplicateStmt)
+
+	if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+		return fmt.Errorf("mysql products repository: failed exec insert categories products: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MysqlProductRepository) markUnavailableSizes(ctx context.Context, products []model.Product, sizesMap map[string]uint64) error {
+	const updateQuery = "update products_sizes set is_available = 0, quantity = 0, updated_at = NOW() where is_available = 1 and region_id = ? and product_id in ("
+	const notInStmt = ") and (product_id, size_id) not in ("
+
+	var builder strings.Builder
+	builder.WriteString(updateQuery)
+	args := make([]interface{}, 0, len(products)+1)
+	args = append(args, products[0].RegionID)
+
+	for i, product := range products {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("?")
+		args = append(args, product.ID)
+	}
+
+	sizesIndex := 0
+	for _, product := range products {
+		for _, size := range product.Sizes {
+			if sizesIndex == 0 {
+				builder.WriteString(notInStmt)
+			} else {
+				builder.WriteString(", ")
+			}
+
+			builder.WriteString("(?, ?)")
+			args = append(args, product.ID, sizesMap[size.Name])
+			sizesIndex++
+		}
+	}
+
+	builder.WriteString(")")
+
+	if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+		return fmt.Errorf("mysql products repository: failed exec mark unavailable sizes: %w", err)
 	}
 
 	return nil
@@ -143,12 +396,13 @@ func (r *MysqlProductRepository) updateSizes(ctx context.Context, products []mod
 		}
 	}
 
-	insertBuilder.WriteString(onDuplicateStmt)
-
-	if len(sizeMap) > 1 {
-		selectBuilder.WriteString(")")
+	if len(sizeMap) == 0 {
+		return sizeMap, nil
 	}
 
+	insertBuilder.WriteString(onDuplicateStmt)
+	selectBuilder.WriteString(")")
+
 	if _, err := r.conn.ExecContext(ctx, insertBuilder.String(), args...); err != nil {
 		return nil, fmt.Errorf("mysql insert sizes error: %w", err)
 	}
@@ -177,3 +431,143 @@ func (r *MysqlProductRepository) updateSizes(ctx context.Context, products []mod
 
 	return sizeMap, nil
 }
+
+func (r *MysqlProductRepository) saveStockHistory(ctx context.Context, products []model.Product) error {
+	const insertQuery = `insert into
+  products_sizes_stock_history (product_id, size_id, region_id, quantity)
+select
+  ps.product_id,
+  ps.size_id,
+  ps.region_id,
+  ps.quantity
+from
+  products_sizes as ps
+where
+  not ps.quantity <=> (
+    select h.quantity
+    from products_sizes_stock_history as h
+    where h.product_id = ps.product_id and h.size_id = ps.size_id and h.region_id = ps.region_id
+    order by h.id desc
+    limit 1
+  ) and
+  ps.region_id = ? and
+  ps.product_id in (`
+
+	var builder strings.Builder
+	builder.WriteString(insertQuery)
+	args := make([]interface{}, 0, len(products)+1)
+	args = append(args, products[0].RegionID)
+
+	for i, product := range products {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("?")
+		args = append(args, product.ID)
+	}
+
+	builder.WriteString(");")
+
+	if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+		return fmt.Errorf("mysql products repository: failed exec insert stock history: %w", err)
+	}
+
+	return nil
+}
+
+// DelistUnseen counts a missed pass for the products of the category not seen since its last pass started
+// and delists the products missed delistAfter times by every source they are linked to.
+// Sizes are counted in the crawled regions for products the category saw, a size missing from the product itself
+// is missing for every source; the sizes of a delisted product are delisted with it.
+func (r *MysqlProductRepository) DelistUnseen(ctx context.Context, categoryID uint64, regionIDs []uint64, delistAfter uint) (int64, error) {
+	const missedLinksQuery = `update
+  categories_products as cp
+  join categories as c on c.id = cp.category_id
+set
+  cp.missed_passes = cp.missed_passes + 1
+where
+  cp.category_id = ? and
+  (cp.last_seen_at is NULL or cp.last_seen_at < c.crawl_pass_started_at);`
+
+	const missedSizesQuery = `update
+  products_sizes as ps
+  join categories_products as cp on cp.product_id = ps.product_id
+  join categories as c on c.id = cp.category_id
+set
+  ps.missed_passes = ps.missed_passes + 1
+where
+  cp.category_id = ? and
+  cp.last_seen_at >= c.crawl_pass_started_at and
+  ps.last_seen_at < c.crawl_pass_started_at and
+  ps.region_id in (`
+
+	const delistProductsQuery = `update
+  products as p
+  join categories_products as cp on cp.product_id = p.id
+set
+  p.delisted_at = NOW()
+where
+  cp.category_id = ? and
+  p.delisted_at is NULL and
+  not exists (
+    select 1
+    from categories_products as seen
+    where seen.product_id = p.id and seen.missed_passes < ?
+  );`
+
+	const delistSizesQuery = `update
+  products_sizes as ps
+  join products as p on p.id = ps.product_id
+  join categories_products as cp on cp.product_id = ps.product_id
+set
+  ps.is_delisted = 1,
+  ps.is_available = 0,
+  ps.quantity = 0
+where
+  cp.category_id = ? and
+  ps.is_delisted = 0 and
+  (ps.missed_passes >= ? or p.delisted_at is not NULL);`
+
+	if _, err := r.conn.ExecContext(ctx, missedLinksQuery, categoryID); err != nil {
+		return 0, fmt.Errorf("mysql products repository: failed exec count missed products: %w", err)
+	}
+
+	if len(regionIDs) > 0 {
+		var builder strings.Builder
+		builder.WriteString(missedSizesQuery)
+		args := make([]interface{}, 0, len(regionIDs)+1)
+		args = append(args, categoryID)
+
+		for i, regionID := range regionIDs {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+
+			builder.WriteString("?")
+			args = append(args, regionID)
+		}
+
+		builder.WriteString(");")
+
+		if _, err := r.conn.ExecContext(ctx, builder.String(), args...); err != nil {
+			return 0, fmt.Errorf("mysql products repository: failed exec count missed sizes: %w", err)
+		}
+	}
+
+	result, err := r.conn.ExecContext(ctx, delistProductsQuery, categoryID, delistAfter)
+	if err != nil {
+		return 0, fmt.Errorf("mysql products repository: failed exec delist products: %w", err)
+	}
+
+	if _, err = r.conn.ExecContext(ctx, delistSizesQuery, categoryID, delistAfter); err != nil {
+		return 0, fmt.Errorf("mysql products repository: failed exec delist sizes: %w", err)
+	}
+
+	delisted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("mysql products repository: delist products rows affected: %w", err)
+	}
+
+	return delisted, nil
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql/mysqltest"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const testRegionID = 1
+
+type sizeState struct {
+	isAvailable bool
+	quantity    uint32
+}
+
+func addTestCategory(t *testing.T, conn *mysql.Connection) uint64 {
+	t.Helper()
+
+	id, err := NewMysqlCategoryRepository(log.NewNop(), conn).AddCategory(context.Background(), model.Category{
+		Marketplace: model.MarketplaceWildberries,
+		Name:        "test_dresses",
+		Title:       "Платья",
+		Emoji:       "👗",
+		RequestURL:  "http://127.0.0.1/catalog?page=%d",
+		ProductURL:  "http://127.0.0.1/catalog/%d/detail.aspx",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func testProduct(categoryID uint64, sizes ...model.ProductSize) model.Product {
+	return model.Product{
+		ExternalID:  1001,
+		Marketplace: model.MarketplaceWildberries,
+		CategoryID:  categoryID,
+		RegionID:    testRegionID,
+		Name:        "Платье миди",
+		URL:         "http://127.0.0.1/catalog/1001/detail.aspx",
+		Sizes:       sizes,
+	}
+}
+
+func testSize(name string, quantity uint32) model.ProductSize {
+	return model.ProductSize{
+		Name:         name,
+		CurrentPrice: 350000,
+		BasicPrice:   500000,
+		ProductPrice: 350000,
+		Quantity:     quantity,
+	}
+}
+
+func sizeStates(t *testing.T, conn *mysql.Connection, externalID uint64) map[string]sizeState {
+	t.Helper()
+
+	const query = `select s.name, ps.is_available, ps.quantity
+from products_sizes as ps join sizes as s on s.id = ps.size_id join products as p on p.id = ps.product_id
+where p.external_id = ? and ps.region_id = ?;`
+
+	rows, err := conn.Query(query, externalID, testRegionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseRows(rows)
+
+	result := make(map[string]sizeState)
+	for rows.Next() {
+		var name string
+		var state sizeState
+		if err = rows.Scan(&name, &state.isAvailable, &state.quantity); err != nil {
+			t.Fatal(err)
+		}
+		result[name] = state
+	}
+
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestMysqlProductRepositoryUpdateSizesAvailability(t *testing.T) {
+	conn := mysqltest.New(t)
+	repository := NewMysqlProductRepository(log.NewNop(), conn)
+	categoryID := addTestCategory(t, conn)
+
+	steps := []struct {
+		name     string
+		sizes    []model.ProductSize
+		expected map[string]sizeState
+	}{
+		{
+			name:  "listed",
+			sizes: []model.ProductSize{testSize("42", 5), testSize("44", 2)},
+			expected: map[string]sizeState{
+				"42": {isAvailable: true, quantity: 5},
+				"44": {isAvailable: true, quantity: 2},
+			},
+		},
+		{
+			name:  "size vanished",
+			sizes: []model.ProductSize{testSize("42", 5)},
+			expected: map[string]sizeState{
+				"42": {isAvailable: true, quantity: 5},
+				"44": {isAvailable: false},
+			},
+		},
+		{
+			name:  "size returned",
+			sizes: []model.ProductSize{testSize("42", 5), testSize("44", 1)},
+			expected: map[string]sizeState{
+				"42": {isAvailable: true, quantity: 5},
+				"44": {isAvailable: true, quantity: 1},
+			},
+		},
+		{
+			name:  "sold out",
+			sizes: []model.ProductSize{testSize("42", 0), testSize("44", 1)},
+			expected: map[string]sizeState{
+				"42": {isAvailable: false},
+				"44": {isAvailable: true, quantity: 1},
+			},
+		},
+		{
+			name:  "restocked",
+			sizes: []model.ProductSize{testSize("42", 3), testSize("44", 1)},
+			expected: map[string]sizeState{
+				"42": {isAvailable: true, quantity: 3},
+				"44": {isAvailable: true, quantity: 1},
+			},
+		},
+	}
+
+	for _, step := range steps {
+		if _, err := repository.Update(context.Background(), []model.Product{testProduct(categoryID, step.sizes...)}); err != nil {
+			t.Fatalf("%s: update failed: %v", step.name, err)
+		}
+
+		if states := sizeStates(t, conn, 1001); !maps.Equal(states, step.expected) {
+			t.Errorf("%s: expected sizes %+v, got %+v", step.name, step.expected, states)
+		}
+	}
+}
+
+func TestMysqlProductRepositoryUpdateNewSoldOutSize(t *testing.T) {
+	conn := mysqltest.New(t)
+	repository := NewMysqlProductRepository(log.NewNop(), conn)
+	categoryID := addTestCategory(t, conn)
+
+	if _, err := repository.Update(context.Background(), []model.Product{testProduct(categoryID, testSize("42", 0))}); err != nil {
+		t.Fatal(err)
+	}
+
+	if states := sizeStates(t, conn, 1001); states["42"] != (sizeState{}) {
+		t.Errorf("expected a size inserted without stock unavailable, got %+v", states)
+	}
+}
+
+func TestMysqlProductRepositoryUpdatePriceChangedAt(t *testing.T) {
+	conn := mysqltest.New(t)
+	repository := NewMysqlProductRepository(log.NewNop(), conn)
+	categoryID := addTestCategory(t, conn)
+
+	isPriceChanged := func() bool {
+		t.Helper()
+
+		var isChanged bool
+		if err := conn.QueryRow("select price_changed_at is not NULL from products_sizes where product_id = (select id from products where external_id = ?);", 1001).Scan(&isChanged); err != nil {
+			t.Fatal(err)
+		}
+
+		return isChanged
+	}
+
+	size := testSize("42", 5)
+	for i := 0; i < 2; i++ {
+		if _, err := repository.Update(context.Background(), []model.Product{testProduct(categoryID, size)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if isPriceChanged() {
+		t.Fatalf("expected an unchanged price without price_changed_at")
+	}
+
+	size.ProductPrice = 300000
+	if _, err := repository.Update(context.Background(), []model.Product{testProduct(categoryID, size)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !isPriceChanged() {
+		t.Errorf("expected a changed product price to set price_changed_at")
+	}
+}
+
+func TestMysqlProductRepositoryUpdateKeepsMarketplacesApart(t *testing.T) {
+	conn := mysqltest.New(t)
+	repository := NewMysqlProductRepository(log.NewNop(), conn)
+	categoryID := addTestCategory(t, conn)
+
+	wbProduct := testProduct(categoryID, testSize("42", 5))
+	ozonProduct := testProduct(categoryID, testSize("42", 1))
+	ozonProduct.Marketplace = model.MarketplaceOzon
+
+	var ids []uint64
+	for _, product := range []model.Product{wbProduct, ozonProduct} {
+		newProducts, err := repository.Update(context.Background(), []model.Product{product})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(newProducts) != 1 || newProducts[0].ExternalID != 1001 {
+			t.Fatalf("expected the %s product saved as new, got %+v", product.Marketplace, newProducts)
+		}
+		ids = append(ids, newProducts[0].ID)
+	}
+
+	if ids[0] == ids[1] {
+		t.Fatalf("expected the same id of two marketplaces saved as two products, got %v", ids)
+	}
+
+	newProducts, err := repository.Update(context.Background(), []model.Product{ozonProduct})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(newProducts) != 0 {
+		t.Errorf("expected the saved product not new, got %+v", newProducts)
+	}
+
+	var quantity uint32
+	if err = conn.QueryRow("select quantity from products_sizes where product_id = ?;", ids[0]).Scan(&quantity); err != nil {
+		t.Fatal(err)
+	}
+
+	if quantity != 5 {
+		t.Errorf("expected the wildberries sizes untouched by the ozon product, got quantity %d", quantity)
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+type MysqlRegionRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlRegionRepository(conn *mysql.Connection) *MysqlRegionRepository {
+	return &MysqlRegionRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlRegionRepository) GetRegions(ctx context.Context) ([]model.Region, error) {
+	const query = "select id, name, dest, is_default from regions order by is_default desc, name;"
+	return r.queryRegions(ctx, "get regions", query)
+}
+
+// GetCrawlRegions returns the default region and every region of chats that track products of the category.
+func (r *MysqlRegionRepository) GetCrawlRegions(ctx context.Context, categoryID uint64) ([]model.Region, error) {
+	const query = `select
+  r.id,
+  r.name,
+  r.dest,
+  r.is_default
+from
+  regions as r
+where
+  r.is_default = 1 or
+  exists (
+    select 1
+    from chats as c join tracking_settings as ts on ts.chat_id = c.chat_id
+    where c.region_id = r.id and ts.category_id = ?
+  ) or
+  exists (
+    select 1
+    from chats as c
+      join stock_subscriptions as ss on ss.chat_id = c.chat_id
+      join categories_products as cp on cp.product_id = ss.product_id
+    where c.region_id = r.id and cp.category_id = ?
+  )
+order by
+  r.is_default desc,
+  r.id;`
+	return r.queryRegions(ctx, "get crawl regions", query, categoryID, categoryID)
+}
+
+func (r *MysqlRegionRepository) GetRegion(ctx context.Context, id uint64) (model.Region, error) {
+	const query = "select id, name, dest, is_default from regions where id = ?;"
+
+	var region model.Region
+	if err := r.conn.QueryRowContext(ctx, query, id).Scan(&region.ID, &region.Name, &region.Dest, &region.IsDefault); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return region, model.ErrRegionNotFound
+		}
+		return region, fmt.Errorf("mysql get region error: %w", err)
+	}
+
+	return region, nil
+}
+
+// GetChatRegion returns the region chosen in the chat or the default one for chats that never chose.
+func (r *MysqlRegionRepository) GetChatRegion(ctx context.Context, chatID int64) (model.Region, error) {
+	const query = `select
+  r.id,
+  r.name,
+  r.dest,
+  r.is_default
+from
+  regions as r
+where
+  r.id = coalesce(
+    (select region_id from chats where chat_id = ?),
+    (select id from regions where is_default = 1 limit 1)
+  );`
+
+	var region model.Region
+	if err := r.conn.QueryRowContext(ctx, query, chatID).Scan(&region.ID, &region.Name, &region.Dest, &region.IsDefault); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return region, model.ErrRegionNotFound
+		}
+		return region, fmt.Errorf("mysql get chat region error: %w", err)
+	}
+
+	return region, nil
+}
+
+func (r *MysqlRegionRepository) SetChatRegion(ctx context.Context, chatID int64, regionID uint64) error {
+	const query = `insert into
+  chats (chat_id, region_id)
+values
+  (?, ?) as new_values on duplicate key
+update
+  region_id = new_values.region_id,
+  updated_at = NOW();`
+
+	if _, err := r.conn.ExecContext(ctx, query, chatID, regionID); err != nil {
+		return fmt.Errorf("mysql update chat region error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MysqlRegionRepository) AddRegion(ctx context.Context, region model.Region) (uint64, error) {
+	const query = `insert into
+  regions (name, dest)
+values
+  (?, ?) as new_values on duplicate key
+update
+  name = new_values.name,
+  updated_at = NOW();`
+
+	if _, err := r.conn.ExecContext(ctx, query, region.Name, region.Dest); err != nil {
+		return 0, fmt.Errorf("mysql insert regions error: %w", err)
+	}
+
+	var id uint64
+	if err := r.conn.QueryRowContext(ctx, "select id from regions where dest = ?;", region.Dest).Scan(&id); err != nil {
+		return 0, fmt.Errorf("mysql get region id error: %w", err)
+	}
+
+	return id, nil
+}
+
+func (r *MysqlRegionRepository) queryRegions(ctx context.Context, name string, query string, args ...interface{}) ([]model.Region, error) {
+	rows, err := r.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("mysql %s error: %w", name, err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []model.Region
+	for rows.Next() {
+		var region model.Region
+
+		if err = rows.Scan(&region.ID, &region.Name, &region.Dest, &region.IsDefault); err != nil {
+			return nil, fmt.Errorf("mysql scan %s row error: %w", name, err)
+		}
+
+		result = append(result, region)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql %s rows error: %w", name, err)
+	}
+
+	return result, nil
+}
@@ -19,17 +19,21 @@ func NewMysqlSizeRepository(conn *mysql.Connection) *MysqlSizeRepository {
 }
 
 func (r *MysqlSizeRepository) GetSizesInfo(ctx context.Context, categoryID uint64) ([]model.SizeInfo, error) {
-	const query = `select ps.size_id, s.name, count(ps.size_id) as c
+	const query = `select ps.size_id, s.name, count(distinct ps.product_id) as c
 from products_sizes as ps
-left join products as p on p.id = ps.product_id
+join categories_products as cp on cp.product_id = ps.product_id
 left join sizes as s on s.id = ps.size_id
-where p.category_id = ?
+where cp.category_id = ? and ps.is_delisted = 0
 group by ps.size_id
-having c >= ?;`
+having c >= if((select type from categories where id = ?) <> ?, ?, ?);`
 
-	const itemsCount = 100
+	// search, brand and seller sources are much smaller than catalog sections, every size found is worth offering
+	const (
+		itemsCount       = 100
+		sourceItemsCount = 1
+	)
 
-	rows, err := r.conn.QueryContext(ctx, query, categoryID, itemsCount)
+	rows, err := r.conn.QueryContext(ctx, query, categoryID, categoryID, model.CategoryTypeCatalog, sourceItemsCount, itemsCount)
 	if err != nil {
 		return nil, fmt.Errorf("mysql products repository: failed get sizes info: %w", err)
 	}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+type MysqlStatsRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlStatsRepository(conn *mysql.Connection) *MysqlStatsRepository {
+	return &MysqlStatsRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlStatsRepository) GetStats(ctx context.Context) (model.Stats, error) {
+	const totalsQuery = `select
+  (select count(*) from chats) as chats_count,
+  (select count(*) from tracking_settings) as trackings_count,
+  (select count(*) from products) as products_count;`
+
+	const categoriesQuery = `select
+  c.title,
+  c.emoji,
+  count(cp.product_id),
+  coalesce(date_format(c.crawled_at, '%d.%m.%Y %H:%i'), '')
+from
+  categories as c
+  left join categories_products as cp on cp.category_id = c.id
+group by
+  c.id, c.title, c.emoji, c.crawled_at
+order by
+  c.id;`
+
+	var stats model.Stats
+	if err := r.conn.QueryRowContext(ctx, totalsQuery).Scan(
+		&stats.ChatsCount,
+		&stats.TrackingsCount,
+		&stats.ProductsCount,
+	); err != nil {
+		return stats, fmt.Errorf("mysql get stats error: %w", err)
+	}
+
+	rows, err := r.conn.QueryContext(ctx, categoriesQuery)
+	if err != nil {
+		return stats, fmt.Errorf("mysql get categories stats error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	for rows.Next() {
+		var item model.CategoryStats
+
+		if err = rows.Scan(&item.CategoryTitle, &item.CategoryEmoji, &item.ProductsCount, &item.LastCrawl); err != nil {
+			return stats, fmt.Errorf("mysql scan categories stats row error: %w", err)
+		}
+
+		stats.Categories = append(stats.Categories, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return stats, fmt.Errorf("mysql get categories stats rows error: %w", err)
+	}
+
+	return stats, nil
+}
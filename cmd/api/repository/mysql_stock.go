@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+)
+
+type MysqlStockRepository struct {
+	conn *mysql.Connection
+}
+
+func NewMysqlStockRepository(conn *mysql.Connection) *MysqlStockRepository {
+	return &MysqlStockRepository{
+		conn: conn,
+	}
+}
+
+func (r *MysqlStockRepository) AddSubscription(ctx context.Context, subscription model.StockSubscription) error {
+	const query = `insert ignore into
+  stock_subscriptions (chat_id, product_id, size_id)
+values
+  (?, ?, ?);`
+
+	if _, err := r.conn.ExecContext(ctx, query, subscription.ChatID, subscription.ProductID, subscription.SizeID); err != nil {
+		return fmt.Errorf("mysql insert stock_subscriptions error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MysqlStockRepository) DeleteSubscription(ctx context.Context, subscription model.StockSubscription) error {
+	const query = "delete from stock_subscriptions where chat_id = ? and product_id = ? and size_id = ?"
+	if _, err := r.conn.ExecContext(ctx, query, subscription.ChatID, subscription.ProductID, subscription.SizeID); err != nil {
+		return fmt.Errorf("mysql delete from stock_subscriptions error: %w", err)
+	}
+	return nil
+}
+
+func (r *MysqlStockRepository) FindBackInStock(ctx context.Context, categoryID uint64) ([]model.StockResult, error) {
+	const query = `select
+  ss.chat_id,
+  ss.product_id,
+  p.name,
+  p.url,
+  ss.size_id,
+  s.name,
+  ps.current_price
+from
+  stock_subscriptions as ss
+  join chats as ch on ch.chat_id = ss.chat_id
+  join products as p on p.id = ss.product_id
+  join categories_products as cp on cp.product_id = ss.product_id
+  join products_sizes as ps on ps.product_id = ss.product_id and ps.size_id = ss.size_id and ps.region_id = ch.region_id
+  join sizes as s on s.id = ss.size_id
+where
+  cp.category_id = ? and
+  p.delisted_at is NULL and
+  ps.is_available = 1;`
+
+	rows, err := r.conn.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("mysql query find back in stock error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []model.StockResult
+	for rows.Next() {
+		var item model.StockResult
+
+		if err = rows.Scan(
+			&item.ChatID,
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductURL,
+			&item.SizeID,
+			&item.Size,
+			&item.CurrentPrice,
+		); err != nil {
+			return nil, fmt.Errorf("mysql scan find back in stock row error: %w", err)
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql find back in stock rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetProductStock finds the product by its marketplace id, a product of any marketplace is found without the marketplace.
+// The id found on several marketplaces is ambiguous.
+func (r *MysqlStockRepository) GetProductStock(ctx context.Context, chatID int64, marketplace model.Marketplace, externalID uint64) (model.ProductStock, error) {
+	const productQuery = "select id, name, url from products where external_id = ? and (? = '' or marketplace = ?) limit 2;"
+	const sizesQuery = `select
+  ps.size_id,
+  s.name,
+  ps.is_available
+from
+  products_sizes as ps
+  join sizes as s on s.id = ps.size_id
+where
+  ps.product_id = ? and
+  ps.region_id = coalesce(
+    (select region_id from chats where chat_id = ?),
+    (select id from regions where is_default = 1 limit 1)
+  )
+order by
+  s.name;`
+
+	var result model.ProductStock
+	products, err := r.conn.QueryContext(ctx, productQuery, externalID, marketplace, marketplace)
+	if err != nil {
+		return result, fmt.Errorf("mysql get product error: %w", err)
+	}
+
+	defer r.conn.CloseRows(products)
+
+	count := 0
+	for products.Next() {
+		if err = products.Scan(&result.ProductID, &result.ProductName, &result.ProductURL); err != nil {
+			return result, fmt.Errorf("mysql scan product row error: %w", err)
+		}
+		count++
+	}
+
+	if err = products.Err(); err != nil {
+		return result, fmt.Errorf("mysql get product rows error: %w", err)
+	}
+
+	if count == 0 {
+		return model.ProductStock{}, model.ErrProductNotFound
+	}
+
+	if count > 1 {
+		return model.ProductStock{}, model.ErrAmbiguousProduct
+	}
+
+	rows, err := r.conn.QueryContext(ctx, sizesQuery, result.ProductID, chatID)
+	if err != nil {
+		return result, fmt.Errorf("mysql get product sizes error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	for rows.Next() {
+		var item model.ProductSizeStock
+
+		if err = rows.Scan(&item.SizeID, &item.Size, &item.IsAvailable); err != nil {
+			return result, fmt.Errorf("mysql scan product sizes row error: %w", err)
+		}
+
+		result.Sizes = append(result.Sizes, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("mysql get product sizes rows error: %w", err)
+	}
+
+	return result, nil
+}
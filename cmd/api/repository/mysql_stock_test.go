@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql/mysqltest"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+func TestMysqlStockRepositoryGetProductStock(t *testing.T) {
+	conn := mysqltest.New(t)
+	productRepository := NewMysqlProductRepository(log.NewNop(), conn)
+	stockRepository := NewMysqlStockRepository(conn)
+	categoryID := addTestCategory(t, conn)
+
+	ids := make(map[model.Marketplace]uint64)
+	for _, product := range []model.Product{
+		testProduct(categoryID, testSize("42", 5)),
+		{ExternalID: 1001, Marketplace: model.MarketplaceOzon, CategoryID: categoryID, RegionID: testRegionID, Name: "Платье макси", Sizes: []model.ProductSize{testSize("44", 0)}},
+		{ExternalID: 2001, Marketplace: model.MarketplaceOzon, CategoryID: categoryID, RegionID: testRegionID, Name: "Юбка", Sizes: []model.ProductSize{testSize("44", 0)}},
+	} {
+		newProducts, err := productRepository.Update(context.Background(), []model.Product{product})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if product.ExternalID == 1001 {
+			ids[product.Marketplace] = newProducts[0].ID
+		}
+	}
+
+	tests := []struct {
+		name        string
+		marketplace model.Marketplace
+		externalID  uint64
+		productID   uint64
+		err         error
+	}{
+		{name: "wildberries link", marketplace: model.MarketplaceWildberries, externalID: 1001, productID: ids[model.MarketplaceWildberries]},
+		{name: "ozon link", marketplace: model.MarketplaceOzon, externalID: 1001, productID: ids[model.MarketplaceOzon]},
+		{name: "id of one marketplace", externalID: 2001},
+		{name: "id of several marketplaces", externalID: 1001, err: model.ErrAmbiguousProduct},
+		{name: "unknown id", externalID: 3001, err: model.ErrProductNotFound},
+		{name: "id of another marketplace", marketplace: model.MarketplaceWildberries, externalID: 2001, err: model.ErrProductNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, err := stockRepository.GetProductStock(context.Background(), 100, tt.marketplace, tt.externalID)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			if tt.productID != 0 && product.ProductID != tt.productID {
+				t.Errorf("expected product %d, got %d", tt.productID, product.ProductID)
+			}
+
+			if len(product.Sizes) != 1 {
+				t.Errorf("expected the sizes of the product, got %+v", product.Sizes)
+			}
+		})
+	}
+}
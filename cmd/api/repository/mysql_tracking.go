@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
@@ -23,14 +24,28 @@ func NewMysqlTrackingRepository(logger log.Logger, conn *mysql.Connection) *Mysq
 
 func (r *MysqlTrackingRepository) AddTracking(ctx context.Context, settings model.TrackingSettings) error {
 	const query = `insert into
-  tracking_settings (chat_id, size_id, category_id, diff_value)
+  tracking_settings (chat_id, size_id, category_id, type, diff_value, price_type, max_price, stock_threshold)
 values
-  (?, ?, ?, ?) as new_values on duplicate key
+  (?, ?, ?, ?, ?, ?, ?, ?) as new_values on duplicate key
 update
   diff_value = new_values.diff_value,
+  price_type = new_values.price_type,
+  max_price = new_values.max_price,
+  stock_threshold = new_values.stock_threshold,
   updated_at = NOW();`
 
-	_, err := r.conn.ExecContext(ctx, query, settings.ChatID, settings.SizeID, settings.CategoryID, settings.DiffValue)
+	_, err := r.conn.ExecContext(
+		ctx,
+		query,
+		settings.ChatID,
+		settings.SizeID,
+		settings.CategoryID,
+		settings.Type,
+		settings.DiffValue,
+		settings.PriceType,
+		settings.MaxPrice,
+		settings.StockThreshold,
+	)
 	if err != nil {
 		return fmt.Errorf("mysql insert tracking_settings error: %w", err)
 	}
@@ -38,30 +53,68 @@ update
 	return nil
 }
 
+// FindMatchTracking compares prices with the baselines the trackings of the category were last matched against,
+// a size gets its baseline on the first match after it is tracked so older drops are not reported.
 func (r *MysqlTrackingRepository) FindMatchTracking(ctx context.Context, categoryID uint64) ([]model.TrackingResult, error) {
 	const query = `select
-  ps.product_id,
+  m.product_id,
   p.name,
   p.url,
-  ps.size_id,
+  m.size_id,
   s.name,
-  ps.previous_price,
-  ps.current_price,
-  ps.current_price_int,
-  ROUND(((ps.previous_price - ps.current_price) / ps.previous_price * 100)) as diff_percent,
-  ts.chat_id
+  m.previous_price,
+  m.current_price,
+  m.basic_price,
+  m.logistics_price,
+  m.price_type,
+  ROUND((m.previous_price - m.current_price) * 100 / m.previous_price) as diff_percent,
+  m.chat_id
 from
-  products_sizes as ps
-  join products as p on p.id = ps.product_id
-  join tracking_settings as ts on ts.size_id = ps.size_id
-  join sizes as s on s.id = ts.size_id
-  left join tracking_logs as tl on tl.chat_id = ts.chat_id and tl.size_id = ts.size_id and tl.product_id = ps.product_id
+  (
+    select
+      ps.product_id,
+      ps.size_id,
+      ts.chat_id,
+      ts.diff_value,
+      ts.price_type,
+      ps.basic_price,
+      ps.logistics_price,
+      if(ts.price_type = ?, b.product_price, b.price) as previous_price,
+      if(ts.price_type = ?, ps.product_price, ps.current_price) as current_price
+    from
+      products_sizes as ps
+      join tracking_settings as ts on ts.size_id = ps.size_id
+      join chats as ch on ch.chat_id = ts.chat_id and ch.region_id = ps.region_id
+      join categories_products_prices as b on
+        b.category_id = ts.category_id and
+        b.product_id = ps.product_id and
+        b.size_id = ps.size_id and
+        b.region_id = ps.region_id
+    where
+      ts.category_id = ? and
+      ts.type = ? and
+      ps.is_available = 1
+  ) as m
+  join products as p on p.id = m.product_id
+  join categories_products as cp on cp.product_id = m.product_id
+  join sizes as s on s.id = m.size_id
+  left join tracking_logs as tl on tl.chat_id = m.chat_id and tl.size_id = m.size_id and tl.product_id = m.product_id
 where
-  ts.category_id = ? and
-  (tl.price is NULL or tl.price <> ps.current_price_int) and
-  ROUND(((ps.previous_price - ps.current_price) / ps.previous_price * 100)) >= ts.diff_value;`
+  cp.category_id = ? and
+  p.delisted_at is NULL and
+  m.previous_price > 0 and
+  (tl.price is NULL or tl.price <> m.current_price) and
+  ROUND((m.previous_price - m.current_price) * 100 / m.previous_price) >= m.diff_value;`
 
-	rows, err := r.conn.QueryContext(ctx, query, categoryID)
+	rows, err := r.conn.QueryContext(
+		ctx,
+		query,
+		model.PriceTypeProduct,
+		model.PriceTypeProduct,
+		categoryID,
+		model.TrackingTypePriceDrop,
+		categoryID,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("mysql query find match tracking error: %w", err)
 	}
@@ -80,7 +133,9 @@ where
 			&trackingResult.Size,
 			&trackingResult.PreviousPrice,
 			&trackingResult.CurrentPrice,
-			&trackingResult.CurrentPriceInt,
+			&trackingResult.BasicPrice,
+			&trackingResult.LogisticsPrice,
+			&trackingResult.PriceType,
 			&trackingResult.DiffPercent,
 			&trackingResult.ChatID,
 		); err != nil {
@@ -114,6 +169,38 @@ update
 	return nil
 }
 
+// SavePriceBaselines moves the baselines of the price trackings of the category to the current prices once they were matched,
+// every source keeps its own baselines so a drop saved by another source is still a drop for it.
+func (r *MysqlTrackingRepository) SavePriceBaselines(ctx context.Context, categoryID uint64) error {
+	const query = `insert into
+  categories_products_prices (category_id, product_id, size_id, region_id, price, product_price)
+select
+  ts.category_id,
+  ps.product_id,
+  ps.size_id,
+  ps.region_id,
+  ps.current_price,
+  ps.product_price
+from
+  tracking_settings as ts
+  join chats as ch on ch.chat_id = ts.chat_id
+  join categories_products as cp on cp.category_id = ts.category_id
+  join products_sizes as ps on ps.product_id = cp.product_id and ps.size_id = ts.size_id and ps.region_id = ch.region_id
+where
+  ts.category_id = ? and
+  ts.type = ?
+on duplicate key update
+  price = ps.current_price,
+  product_price = ps.product_price,
+  updated_at = NOW();`
+
+	if _, err := r.conn.ExecContext(ctx, query, categoryID, model.TrackingTypePriceDrop); err != nil {
+		return fmt.Errorf("mysql save price baselines error: %w", err)
+	}
+
+	return nil
+}
+
 func (r *MysqlTrackingRepository) DeleteTrackingSettingsByChat(ctx context.Context, chatID int64) error {
 	const query = "delete from tracking_settings where chat_id = ?"
 	if _, err := r.conn.ExecContext(ctx, query, chatID); err != nil {
@@ -122,9 +209,15 @@ func (r *MysqlTrackingRepository) DeleteTrackingSettingsByChat(ctx context.Conte
 	return nil
 }
 
-func (r *MysqlTrackingRepository) DeleteTrackingSettings(ctx context.Context, chatID int64, sizeID uint64, categoryID uint64) error {
-	const query = "delete from tracking_settings where chat_id = ? and size_id = ? and category_id = ?"
-	if _, err := r.conn.ExecContext(ctx, query, chatID, sizeID, categoryID); err != nil {
+func (r *MysqlTrackingRepository) DeleteTrackingSettings(
+	ctx context.Context,
+	chatID int64,
+	sizeID uint64,
+	categoryID uint64,
+	trackingType model.TrackingType,
+) error {
+	const query = "delete from tracking_settings where chat_id = ? and size_id = ? and category_id = ? and type = ?"
+	if _, err := r.conn.ExecContext(ctx, query, chatID, sizeID, categoryID, trackingType); err != nil {
 		return fmt.Errorf("mysql delete from tracking_settings error: %w", err)
 	}
 	return nil
@@ -138,7 +231,11 @@ func (r *MysqlTrackingRepository) GetTrackingSettingsInfo(ctx context.Context, c
   ts.category_id,
   c.title,
   c.emoji,
-  ts.diff_value
+  ts.type,
+  ts.diff_value,
+  ts.price_type,
+  ts.max_price,
+  ts.stock_threshold
 from
   tracking_settings as ts
   join sizes as s on s.id = ts.size_id
@@ -164,7 +261,11 @@ where
 			&trackingSettingsInfo.CategoryID,
 			&trackingSettingsInfo.CategoryTitle,
 			&trackingSettingsInfo.CategoryEmoji,
+			&trackingSettingsInfo.Type,
 			&trackingSettingsInfo.DiffPercent,
+			&trackingSettingsInfo.PriceType,
+			&trackingSettingsInfo.MaxPrice,
+			&trackingSettingsInfo.StockThreshold,
 		); err != nil {
 			return nil, fmt.Errorf("mysql scan tracking settings info row error: %w", err)
 		}
@@ -178,3 +279,199 @@ where
 
 	return result, nil
 }
+
+func (r *MysqlTrackingRepository) FindNewArrivals(ctx context.Context, categoryID uint64, productIDs []uint64) ([]model.NewArrivalResult, error) {
+	const query = `select
+  ts.chat_id,
+  c.title,
+  c.emoji,
+  p.id,
+  p.name,
+  p.url,
+  s.name,
+  ps.current_price
+from
+  tracking_settings as ts
+  join categories as c on c.id = ts.category_id
+  join chats as ch on ch.chat_id = ts.chat_id
+  join categories_products as cp on cp.category_id = ts.category_id
+  join products as p on p.id = cp.product_id
+  join products_sizes as ps on ps.product_id = p.id and ps.size_id = ts.size_id and ps.region_id = ch.region_id
+  join sizes as s on s.id = ts.size_id
+where
+  ts.category_id = ? and
+  ts.type = ? and
+  ps.is_available = 1 and
+  p.delisted_at is NULL and
+  (ts.max_price = 0 or ps.current_price <= ts.max_price) and
+  p.id in (`
+
+	if len(productIDs) == 0 {
+		return nil, nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString(query)
+	args := make([]interface{}, 0, len(productIDs)+2)
+	args = append(args, categoryID, model.TrackingTypeNewArrivals)
+
+	for i, productID := range productIDs {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+
+		builder.WriteString("?")
+		args = append(args, productID)
+	}
+
+	builder.WriteString(")\norder by\n  ts.chat_id, ps.current_price;")
+
+	rows, err := r.conn.QueryContext(ctx, builder.String(), args...)
+	if err != nil {
+		return nil, fmt.Errorf("mysql query find new arrivals error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []model.NewArrivalResult
+	for rows.Next() {
+		var item model.NewArrivalResult
+
+		if err = rows.Scan(
+			&item.ChatID,
+			&item.CategoryTitle,
+			&item.CategoryEmoji,
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductURL,
+			&item.Size,
+			&item.CurrentPrice,
+		); err != nil {
+			return nil, fmt.Errorf("mysql scan find new arrivals row error: %w", err)
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql find new arrivals rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *MysqlTrackingRepository) FindLowStock(ctx context.Context, categoryID uint64) ([]model.LowStockResult, error) {
+	const query = `select
+  ts.chat_id,
+  c.title,
+  c.emoji,
+  p.id,
+  p.name,
+  p.url,
+  ps.size_id,
+  s.name,
+  ps.current_price,
+  ps.quantity
+from
+  tracking_settings as ts
+  join categories as c on c.id = ts.category_id
+  join chats as ch on ch.chat_id = ts.chat_id
+  join categories_products as cp on cp.category_id = ts.category_id
+  join products as p on p.id = cp.product_id
+  join products_sizes as ps on ps.product_id = p.id and ps.size_id = ts.size_id and ps.region_id = ch.region_id
+  join sizes as s on s.id = ts.size_id
+  left join low_stock_logs as l on l.chat_id = ts.chat_id and l.product_id = ps.product_id and l.size_id = ps.size_id
+where
+  ts.category_id = ? and
+  ts.type = ? and
+  ps.is_available = 1 and
+  p.delisted_at is NULL and
+  ps.quantity > 0 and
+  ps.quantity < ts.stock_threshold and
+  (ts.max_price = 0 or ps.current_price <= ts.max_price) and
+  l.chat_id is NULL
+order by
+  ts.chat_id,
+  ps.quantity;`
+
+	rows, err := r.conn.QueryContext(ctx, query, categoryID, model.TrackingTypeLowStock)
+	if err != nil {
+		return nil, fmt.Errorf("mysql query find low stock error: %w", err)
+	}
+
+	defer r.conn.CloseRows(rows)
+
+	var result []model.LowStockResult
+	for rows.Next() {
+		var item model.LowStockResult
+
+		if err = rows.Scan(
+			&item.ChatID,
+			&item.CategoryTitle,
+			&item.CategoryEmoji,
+			&item.ProductID,
+			&item.ProductName,
+			&item.ProductURL,
+			&item.SizeID,
+			&item.Size,
+			&item.CurrentPrice,
+			&item.Quantity,
+		); err != nil {
+			return nil, fmt.Errorf("mysql scan low stock row error: %w", err)
+		}
+
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("mysql find low stock rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *MysqlTrackingRepository) SaveLowStockLog(ctx context.Context, result model.LowStockResult) error {
+	const query = `insert into
+  low_stock_logs (chat_id, product_id, size_id, quantity)
+values
+  (?, ?, ?, ?) as new_values on duplicate key
+update
+  quantity = new_values.quantity;`
+
+	if _, err := r.conn.ExecContext(ctx, query, result.ChatID, result.ProductID, result.SizeID, result.Quantity); err != nil {
+		return fmt.Errorf("mysql insert low_stock_logs error: %w", err)
+	}
+
+	return nil
+}
+
+// ResetLowStockLogs forgets alerts for sizes that were restocked or sold out, so the next drop is reported again.
+func (r *MysqlTrackingRepository) ResetLowStockLogs(ctx context.Context, categoryID uint64) error {
+	const query = `delete l
+from
+  low_stock_logs as l
+  join chats as ch on ch.chat_id = l.chat_id
+  join categories_products as cp on cp.product_id = l.product_id
+  join products_sizes as ps on ps.product_id = l.product_id and ps.size_id = l.size_id and ps.region_id = ch.region_id
+where
+  cp.category_id = ? and
+  (
+    ps.is_available = 0 or
+    not exists (
+      select 1
+      from tracking_settings as ts join categories_products as tcp on tcp.category_id = ts.category_id
+      where
+        tcp.product_id = l.product_id and
+        ts.chat_id = l.chat_id and
+        ts.size_id = l.size_id and
+        ts.type = ? and
+        ps.quantity < ts.stock_threshold
+    )
+  );`
+
+	if _, err := r.conn.ExecContext(ctx, query, categoryID, model.TrackingTypeLowStock); err != nil {
+		return fmt.Errorf("mysql delete low_stock_logs error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	maxCategoryNameLength  = 64
+	maxCategoryTitleLength = 21
+	maxCategoryEmojiLength = 10
+
+	minSearchQueryLength = 2
+	maxSearchQueryLength = 100
+)
+
+type CategoryResolver interface {
+	ResolveSearch(query string) model.Category
+	ResolveBrand(brand model.Brand) model.Category
+	ResolveSeller(seller model.Seller) model.Category
+	ResolveNode(node model.CategoryNode) (model.Category, error)
+	GetCategoryTree(ctx context.Context) ([]model.CategoryNode, error)
+}
+
+type CategoryAddRepository interface {
+	GetCategoryByName(ctx context.Context, name string) (model.Category, error)
+	AddCategory(ctx context.Context, category model.Category) (uint64, error)
+}
+
+type BrandRepository interface {
+	GetBrand(ctx context.Context, id uint64) (model.Brand, error)
+	FindBrand(ctx context.Context, name string) (model.Brand, error)
+	GetSeller(ctx context.Context, id uint64) (model.Seller, error)
+	FindSeller(ctx context.Context, name string) (model.Seller, error)
+}
+
+type CategoryTreeRepository interface {
+	SaveTree(ctx context.Context, nodes []model.CategoryNode, syncedAt time.Time) (int64, error)
+	LinkCategories(ctx context.Context) error
+	LinkCategory(ctx context.Context, nodeID uint64, categoryID uint64) error
+	GetNode(ctx context.Context, id uint64) (model.CategoryNode, error)
+}
+
+type CategoryService struct {
+	logger          log.Logger
+	resolver        CategoryResolver
+	pageResolver    PageResolver
+	client          ProductClient
+	repository      CategoryAddRepository
+	brandRepository BrandRepository
+	treeRepository  CategoryTreeRepository
+}
+
+func NewCategoryService(
+	logger log.Logger,
+	resolver CategoryResolver,
+	pageResolver PageResolver,
+	client ProductClient,
+	repository CategoryAddRepository,
+	brandRepository BrandRepository,
+	treeRepository CategoryTreeRepository,
+) *CategoryService {
+	return &CategoryService{
+		logger:          logger,
+		resolver:        resolver,
+		pageResolver:    pageResolver,
+		client:          client,
+		repository:      repository,
+		brandRepository: brandRepository,
+		treeRepository:  treeRepository,
+	}
+}
+
+// SyncCategoryTree replaces the category tree with the current WB menu.
+func (s *CategoryService) SyncCategoryTree(ctx context.Context) error {
+	nodes, err := s.resolver.GetCategoryTree(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		// an empty menu is a broken response, keeping the old tree is better than wiping the picker
+		return errors.New("category tree sync: menu is empty")
+	}
+
+	// datetime keeps whole seconds, the stale nodes must compare strictly older
+	deleted, err := s.treeRepository.SaveTree(ctx, nodes, time.Now().Truncate(time.Second))
+	if err != nil {
+		return err
+	}
+
+	if err = s.treeRepository.LinkCategories(ctx); err != nil {
+		return err
+	}
+
+	s.logger.Info().Int("nodes", len(nodes)).Int64("deleted", deleted).Msg("category tree synced")
+	return nil
+}
+
+// EnableCategory adds the catalog category of the tree node and links it, the picker shows linked nodes only.
+// Categories of the same cat id are linked first so a category added by url under another name is not added twice.
+func (s *CategoryService) EnableCategory(ctx context.Context, nodeID uint64, title string, emoji string) (model.Category, error) {
+	if err := s.treeRepository.LinkCategories(ctx); err != nil {
+		return model.Category{}, err
+	}
+
+	node, err := s.treeRepository.GetNode(ctx, nodeID)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	if node.Category.ID > 0 {
+		return model.Category{}, model.ErrCategoryExists
+	}
+
+	category, err := s.resolver.ResolveNode(node)
+	if err != nil {
+		return model.Category{}, fmt.Errorf("%w: %w", model.ErrInvalidCategory, err)
+	}
+
+	if title != "" {
+		category.Title = title
+	}
+	category.Emoji = emoji
+
+	if err = validateCategory(category); err != nil {
+		return model.Category{}, err
+	}
+
+	_, err = s.repository.GetCategoryByName(ctx, category.Name)
+	if err == nil {
+		return model.Category{}, model.ErrCategoryExists
+	}
+	if !errors.Is(err, model.ErrCategoryNotFound) {
+		return model.Category{}, err
+	}
+
+	category, err = s.addCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	if err = s.treeRepository.LinkCategory(ctx, node.ID, category.ID); err != nil {
+		return model.Category{}, err
+	}
+
+	return category, nil
+}
+
+func (s *CategoryService) AddCategory(ctx context.Context, pageURL string, title string, emoji string) (model.Category, error) {
+	category, err := s.pageResolver.ResolveCategory(ctx, pageURL)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	if title != "" {
+		category.Title = title
+	}
+	category.Emoji = emoji
+
+	if err = validateCategory(category); err != nil {
+		return model.Category{}, err
+	}
+
+	_, err = s.repository.GetCategoryByName(ctx, category.Name)
+	if err == nil {
+		return model.Category{}, model.ErrCategoryExists
+	}
+	if !errors.Is(err, model.ErrCategoryNotFound) {
+		return model.Category{}, err
+	}
+
+	category, err = s.addCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	// categories added by url show up in the picker once linked to their tree node
+	if err = s.treeRepository.LinkCategories(ctx); err != nil {
+		s.logger.Error().Err(err).Str("category", category.Name).Msg("failed link category to tree")
+	}
+
+	return category, nil
+}
+
+// AddSearch returns the category of the search query.
+func (s *CategoryService) AddSearch(ctx context.Context, query string) (model.Category, error) {
+	category := s.resolver.ResolveSearch(query)
+
+	length := utf8.RuneCountInString(category.Query)
+	if length < minSearchQueryLength || length > maxSearchQueryLength {
+		return model.Category{}, fmt.Errorf("%w: query must be %d-%d characters", model.ErrInvalidQuery, minSearchQueryLength, maxSearchQueryLength)
+	}
+
+	return s.addSource(ctx, category)
+}
+
+// AddBrand returns the category of the brand given by id or by name, names are looked up among crawled products.
+func (s *CategoryService) AddBrand(ctx context.Context, value string) (model.Category, error) {
+	value = strings.TrimSpace(value)
+
+	var brand model.Brand
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err == nil && id > 0 {
+		// a brand we have never crawled is still valid, the test fetch tells whether it exists
+		brand, err = s.brandRepository.GetBrand(ctx, id)
+		if errors.Is(err, model.ErrBrandNotFound) {
+			brand, err = model.Brand{ID: id}, nil
+		}
+	} else {
+		brand, err = s.brandRepository.FindBrand(ctx, value)
+	}
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	return s.addSource(ctx, s.resolver.ResolveBrand(brand))
+}
+
+// AddSeller returns the category of the seller given by id or by name, names are looked up among enriched products.
+func (s *CategoryService) AddSeller(ctx context.Context, value string) (model.Category, error) {
+	value = strings.TrimSpace(value)
+
+	var seller model.Seller
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err == nil && id > 0 {
+		seller, err = s.brandRepository.GetSeller(ctx, id)
+		if errors.Is(err, model.ErrSellerNotFound) {
+			seller, err = model.Seller{ID: id}, nil
+		}
+	} else {
+		seller, err = s.brandRepository.FindSeller(ctx, value)
+	}
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	return s.addSource(ctx, s.resolver.ResolveSeller(seller))
+}
+
+// addSource adds a category subscribed by users, sources are shared between chats so an existing one is reused.
+func (s *CategoryService) addSource(ctx context.Context, category model.Category) (model.Category, error) {
+	existing, err := s.repository.GetCategoryByName(ctx, category.Name)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, model.ErrCategoryNotFound) {
+		return model.Category{}, err
+	}
+
+	return s.addCategory(ctx, category)
+}
+
+func (s *CategoryService) addCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	var count int
+	err := s.client.StreamProducts(ctx, model.ProductsRequest{
+		Page:        1,
+		Type:        category.Type,
+		Marketplace: category.Marketplace,
+		Category:    category.Name,
+		RequestURL:  category.RequestURL,
+		ProductURL:  category.ProductURL,
+		Query:       category.Query,
+	}, func(model.Product) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		return model.Category{}, fmt.Errorf("category %s test fetch failed: %w", category.Name, err)
+	}
+
+	if count == 0 {
+		return model.Category{}, fmt.Errorf("%w: test fetch of %s returned no products", model.ErrInvalidCategory, category.Name)
+	}
+
+	category.ID, err = s.repository.AddCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+
+	s.logger.Info().
+		Uint64("category_id", category.ID).
+		Str("category", category.Name).
+		Str("request_url", category.RequestURL).
+		Str("query", category.Query).
+		Msg("category added")
+
+	return category, nil
+}
+
+func validateCategory(category model.Category) error {
+	if category.Name == "" || utf8.RuneCountInString(category.Name) > maxCategoryNameLength {
+		return fmt.Errorf("%w: name must be 1-%d characters", model.ErrInvalidCategory, maxCategoryNameLength)
+	}
+
+	if category.Title == "" || utf8.RuneCountInString(category.Title) > maxCategoryTitleLength {
+		return fmt.Errorf("%w: title must be 1-%d characters", model.ErrInvalidCategory, maxCategoryTitleLength)
+	}
+
+	// title and emoji are passed through callback data separated by ':'
+	if strings.Contains(category.Title, ":") || strings.Contains(category.Emoji, ":") {
+		return fmt.Errorf("%w: title and emoji must not contain ':'", model.ErrInvalidCategory)
+	}
+
+	if category.Emoji == "" || utf8.RuneCountInString(category.Emoji) > maxCategoryEmojiLength {
+		return fmt.Errorf("%w: emoji must be 1-%d characters", model.ErrInvalidCategory, maxCategoryEmojiLength)
+	}
+
+	return validateRequestURL(category.RequestURL)
+}
+
+// validateRequestURL checks the request url takes only the page, catalog urls carry their shard already.
+func validateRequestURL(requestURL string) error {
+	if strings.Count(requestURL, "%") != 1 || !strings.Contains(requestURL, "%d") {
+		return fmt.Errorf("%w: request url must contain %%d for page", model.ErrInvalidCategory)
+	}
+
+	return validateAbsoluteURL(fmt.Sprintf(requestURL, 1))
+}
+
+func validateAbsoluteURL(requestURL string) error {
+	parsedURL, err := url.Parse(requestURL)
+	if err != nil {
+		return fmt.Errorf("%w: request url parse error: %w", model.ErrInvalidCategory, err)
+	}
+
+	if parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("%w: request url must be an absolute https url", model.ErrInvalidCategory)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type ProductDetailsClient interface {
+	GetProductDetails(ctx context.Context, productID uint64) (model.ProductDetails, error)
+}
+
+type ProductDetailsRepository interface {
+	SaveDetails(ctx context.Context, details []model.ProductDetails) error
+}
+
+type EnrichmentService struct {
+	logger            log.Logger
+	client            ProductDetailsClient
+	detailsRepository ProductDetailsRepository
+}
+
+func NewEnrichmentService(logger log.Logger, client ProductDetailsClient, detailsRepository ProductDetailsRepository) *EnrichmentService {
+	return &EnrichmentService{
+		logger:            logger,
+		client:            client,
+		detailsRepository: detailsRepository,
+	}
+}
+
+// HandleProducts requests the cards by the wildberries articles of the products and saves the details under their ids.
+func (s *EnrichmentService) HandleProducts(ctx context.Context, products []model.ProductRef) error {
+	details := make([]model.ProductDetails, 0, len(products))
+
+	for i, product := range products {
+		item, err := s.client.GetProductDetails(ctx, product.ExternalID)
+		if err != nil {
+			if errors.Is(err, model.ErrProductCardNotFound) {
+				s.logger.Debug().Uint64("product_id", product.ID).Msg("product card not found")
+				continue
+			}
+
+			if errors.Is(err, model.ErrRequestLimit) || errors.Is(err, model.ErrCircuitOpen) {
+				s.logger.Warn().Err(err).Int("skipped", len(products)-i).Msg("product enrichment stopped")
+				break
+			}
+
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+
+			s.logger.Error().Err(err).Uint64("product_id", product.ID).Msg("failed get product details")
+			continue
+		}
+
+		// the card is found by the article, the details belong to the product saved for it
+		item.ProductID = product.ID
+		details = append(details, item)
+	}
+
+	return s.detailsRepository.SaveDetails(ctx, details)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+// fakeDetailsClient answers with the error set for an article, other articles get details with the article as their id.
+type fakeDetailsClient struct {
+	errs      map[uint64]error
+	requested []uint64
+}
+
+func (c *fakeDetailsClient) GetProductDetails(_ context.Context, productID uint64) (model.ProductDetails, error) {
+	c.requested = append(c.requested, productID)
+	if err := c.errs[productID]; err != nil {
+		return model.ProductDetails{}, err
+	}
+	return model.ProductDetails{ProductID: productID}, nil
+}
+
+type fakeDetailsRepository struct {
+	saved [][]model.ProductDetails
+}
+
+func (r *fakeDetailsRepository) SaveDetails(_ context.Context, details []model.ProductDetails) error {
+	r.saved = append(r.saved, details)
+	return nil
+}
+
+func TestEnrichmentServiceHandleProducts(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      map[uint64]error
+		requested []uint64
+		saved     []uint64
+		err       error
+	}{
+		{name: "all details", requested: []uint64{101, 102, 103}, saved: []uint64{1, 2, 3}},
+		{
+			name:      "card not found is skipped",
+			errs:      map[uint64]error{102: model.ErrProductCardNotFound},
+			requested: []uint64{101, 102, 103},
+			saved:     []uint64{1, 3},
+		},
+		{
+			name:      "failed product is skipped",
+			errs:      map[uint64]error{101: errors.New("bad gateway")},
+			requested: []uint64{101, 102, 103},
+			saved:     []uint64{2, 3},
+		},
+		{
+			name:      "request limit stops the batch",
+			errs:      map[uint64]error{102: model.ErrRequestLimit},
+			requested: []uint64{101, 102},
+			saved:     []uint64{1},
+		},
+		{
+			name:      "open circuit stops the batch",
+			errs:      map[uint64]error{101: model.ErrCircuitOpen},
+			requested: []uint64{101},
+			saved:     []uint64{},
+		},
+		{
+			name:      "canceled batch is not saved",
+			errs:      map[uint64]error{102: context.Canceled},
+			requested: []uint64{101, 102},
+			err:       context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeDetailsClient{errs: tt.errs}
+			repository := &fakeDetailsRepository{}
+			service := NewEnrichmentService(log.NewNop(), client, repository)
+
+			err := service.HandleProducts(context.Background(), []model.ProductRef{
+				{ID: 1, ExternalID: 101},
+				{ID: 2, ExternalID: 102},
+				{ID: 3, ExternalID: 103},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if !reflect.DeepEqual(client.requested, tt.requested) {
+				t.Errorf("expected requested %v, got %v", tt.requested, client.requested)
+			}
+
+			if tt.err != nil {
+				if len(repository.saved) != 0 {
+					t.Errorf("expected nothing saved, got %v", repository.saved)
+				}
+				return
+			}
+
+			if len(repository.saved) != 1 {
+				t.Fatalf("expected one save, got %d", len(repository.saved))
+			}
+
+			saved := make([]uint64, 0, len(repository.saved[0]))
+			for _, details := range repository.saved[0] {
+				saved = append(saved, details.ProductID)
+			}
+
+			if !reflect.DeepEqual(saved, tt.saved) {
+				t.Errorf("expected saved %v, got %v", tt.saved, saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+)
+
+type PageResolver interface {
+	ResolveCategory(ctx context.Context, pageURL string) (model.Category, error)
+}
+
+type marketplaceAdapter struct {
+	client   ProductClient
+	resolver PageResolver
+	hosts    []string
+}
+
+// MarketplaceRegistry picks the adapter of the category marketplace, it is a ProductClient and a PageResolver itself
+// so crawling and adding categories work the same for every shop.
+type MarketplaceRegistry struct {
+	adapters map[model.Marketplace]marketplaceAdapter
+}
+
+func NewMarketplaceRegistry() *MarketplaceRegistry {
+	return &MarketplaceRegistry{
+		adapters: make(map[model.Marketplace]marketplaceAdapter),
+	}
+}
+
+// Register adds the adapter of the marketplace, hosts are the shop sites category pages are added from.
+func (r *MarketplaceRegistry) Register(marketplace model.Marketplace, client ProductClient, resolver PageResolver, hosts ...string) {
+	r.adapters[marketplace] = marketplaceAdapter{
+		client:   client,
+		resolver: resolver,
+		hosts:    hosts,
+	}
+}
+
+func (r *MarketplaceRegistry) StreamProducts(ctx context.Context, request model.ProductsRequest, yield func(product model.Product) error) error {
+	adapter, ok := r.adapters[request.Marketplace]
+	if !ok {
+		return fmt.Errorf("%w: %q", model.ErrUnknownMarketplace, request.Marketplace)
+	}
+
+	return adapter.client.StreamProducts(ctx, request, yield)
+}
+
+// ResolveCategory resolves the category page with the adapter of the page host, subdomains of the host match too.
+func (r *MarketplaceRegistry) ResolveCategory(ctx context.Context, pageURL string) (model.Category, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return model.Category{}, fmt.Errorf("%w: url parse error: %w", model.ErrInvalidCategory, err)
+	}
+
+	host := parsedURL.Hostname()
+	for _, adapter := range r.adapters {
+		for _, adapterHost := range adapter.hosts {
+			if host == adapterHost || strings.HasSuffix(host, "."+adapterHost) {
+				return adapter.resolver.ResolveCategory(ctx, pageURL)
+			}
+		}
+	}
+
+	return model.Category{}, fmt.Errorf("%w: %s", model.ErrUnknownMarketplace, host)
+}
@@ -3,122 +3,449 @@ package service
 import (
 	"context"
 	"errors"
-	"sync/atomic"
+	"sync"
+	"time"
 
 	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/background"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/queue"
 )
 
+type ProductServiceConfig struct {
+	Concurrency int  `config:"concurrency"`
+	MaxPages    int  `config:"max_pages"`
+	ChunkSize   int  `config:"chunk_size"`
+	DelistAfter uint `config:"delist_after"`
+}
+
 type ProductClient interface {
-	GetProducts(ctx context.Context, request model.ProductsRequest) ([]model.Product, error)
+	StreamProducts(ctx context.Context, request model.ProductsRequest, yield func(product model.Product) error) error
 }
 
 type CategoryRepository interface {
-	GetCategories(ctx context.Context) ([]model.Category, error)
-	GetCategory(ctx context.Context, id uint64) (model.Category, error)
+	GetCategoriesToCrawl(ctx context.Context, crawlIdle bool) ([]model.Category, error)
+	GetCrawlStats(ctx context.Context, id uint64, crawlDuration time.Duration) (model.CategoryCrawlStats, error)
+	UpdateCrawlSchedule(ctx context.Context, id uint64, nextInterval time.Duration, throttleCount uint) error
+	GetCrawlCheckpoint(ctx context.Context, id uint64) (model.CrawlCheckpoint, error)
+	SaveCrawlCheckpoint(ctx context.Context, id uint64, checkpoint model.CrawlCheckpoint) error
+	StartCrawlPass(ctx context.Context, id uint64) error
 }
 
 type ProductUpdateRepository interface {
-	Update(ctx context.Context, products []model.Product) error
+	Update(ctx context.Context, products []model.Product) ([]model.ProductRef, error)
+	DelistUnseen(ctx context.Context, categoryID uint64, regionIDs []uint64, delistAfter uint) (int64, error)
+}
+
+type CrawlRegionRepository interface {
+	GetCrawlRegions(ctx context.Context, categoryID uint64) ([]model.Region, error)
 }
 
 type TrackingNotifier interface {
 	SendNotifications(ctx context.Context, categoryID uint64) error
+	SendNewArrivals(ctx context.Context, categoryID uint64, productIDs []uint64) error
 }
 
 type ProductService struct {
 	logger log.Logger
+	config ProductServiceConfig
 
-	hashCounter uint64
-
-	client ProductClient
+	client    ProductClient
+	scheduler *CrawlScheduler
 
 	categoryRepository CategoryRepository
 	productRepository  ProductUpdateRepository
+	regionRepository   CrawlRegionRepository
 
 	trackingNotifier TrackingNotifier
+	enrichmentQueue  queue.Queue[model.ProductRef]
+
+	// crawling keeps the categories being crawled, the scheduled pool and admin commands must not crawl one twice
+	mu       sync.Mutex
+	crawling map[uint64]struct{}
 }
 
 func NewProductService(
 	logger log.Logger,
+	config ProductServiceConfig,
 	client ProductClient,
+	scheduler *CrawlScheduler,
 	categoryRepository CategoryRepository,
 	productRepository ProductUpdateRepository,
+	regionRepository CrawlRegionRepository,
 	trackingNotifier TrackingNotifier,
+	enrichmentQueue queue.Queue[model.ProductRef],
 ) *ProductService {
 	return &ProductService{
 		logger:             logger,
+		config:             config,
 		client:             client,
+		scheduler:          scheduler,
 		categoryRepository: categoryRepository,
 		productRepository:  productRepository,
+		regionRepository:   regionRepository,
 		trackingNotifier:   trackingNotifier,
+		enrichmentQueue:    enrichmentQueue,
+		crawling:           make(map[uint64]struct{}),
 	}
 }
 
 func (s *ProductService) RunUpdateWorkers(ctx context.Context) error {
-	categories, err := s.categoryRepository.GetCategories(ctx)
+	categories, err := s.categoryRepository.GetCategoriesToCrawl(ctx, s.scheduler.CrawlIdle())
 	if err != nil {
 		return err
 	}
 
 	if len(categories) == 0 {
-		s.logger.Warn().Msg("no product categories found")
+		s.logger.Debug().Msg("no product categories to crawl")
 		return nil
 	}
 
-	if len(categories) == 1 {
-		return s.UpdateProducts(ctx, categories[0])
-	}
+	pool := background.NewPool(s.logger, s.config.Concurrency)
+	defer pool.Wait()
 
-	index := atomic.AddUint64(&s.hashCounter, 1) % uint64(len(categories))
-	category := categories[index]
-	s.logger.Info().Uint64("index", index).Str("category", category.Name).Send()
+	for _, category := range categories {
+		if err = pool.Run(ctx, "crawl "+category.Name, func(ctx context.Context) error {
+			s.logger.Info().Str("category", category.Name).Msg("category crawl started")
+			if uErr := s.UpdateProducts(ctx, category); !errors.Is(uErr, model.ErrCrawlInProgress) {
+				return uErr
+			}
+			s.logger.Info().Str("category", category.Name).Msg("category crawl skipped, already running")
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
 
-	return s.UpdateProducts(ctx, category)
+	return nil
 }
 
 func (s *ProductService) UpdateProducts(ctx context.Context, category model.Category) error {
-	request := model.ProductsRequest{
-		Page:       1,
-		Category:   category.Name,
-		CategoryID: category.ID,
-		RequestURL: category.RequestURL,
-		ProductURL: category.ProductURL,
+	if !s.startCrawl(category.ID) {
+		return model.ErrCrawlInProgress
 	}
+	defer s.finishCrawl(category.ID)
 
-	var err error
-	isUpdated := false
+	regions, err := s.regionRepository.GetCrawlRegions(ctx, category.ID)
+	if err != nil {
+		return err
+	}
 
-	for {
-		s.logger.Debug().Int("page", request.Page).Msg("products request")
+	checkpoint, err := s.categoryRepository.GetCrawlCheckpoint(ctx, category.ID)
+	if err != nil {
+		return err
+	}
 
-		var products []model.Product
-		products, err = s.client.GetProducts(ctx, request)
-		if err != nil {
-			break
+	if checkpoint.RegionID == 0 {
+		if err = s.categoryRepository.StartCrawlPass(ctx, category.ID); err != nil {
+			return err
 		}
+	}
 
-		if len(products) == 0 {
-			break
-		}
+	start := time.Now()
+	var result crawlResult
 
-		if err = s.productRepository.Update(ctx, products); err != nil {
-			break
-		}
+	// prices that do not depend on the region are requested once and copied to the other regions
+	var mirrors []model.Region
+	if !category.Marketplace.HasRegionPrices() {
+		regions, mirrors = splitDefaultRegion(regions)
+	}
 
-		isUpdated = true
-		request.Page++
+	next, err := s.crawl(ctx, category, regions, mirrors, checkpoint, &result)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// cards and sellers are only published by wildberries
+	if category.Marketplace == model.MarketplaceWildberries {
+		s.pushEnrichment(ctx, category, result.newProducts)
 	}
 
-	if err != nil && !errors.Is(err, model.ErrRequestLimit) {
+	if sErr := s.categoryRepository.SaveCrawlCheckpoint(ctx, category.ID, next); sErr != nil {
+		s.logger.Error().Err(sErr).Str("category", category.Name).Msg("failed save category crawl checkpoint")
+	}
+
+	isThrottled := errors.Is(err, model.ErrRequestLimit) || errors.Is(err, model.ErrCircuitOpen)
+	isPassFinished := next.Passes > checkpoint.Passes
+	if isPassFinished && s.config.DelistAfter > 0 {
+		// products of a skipped page are unknown, a pass with skipped pages does not count misses
+		if result.skippedPages > 0 {
+			s.logger.Warn().Str("category", category.Name).Uint("skipped_pages", result.skippedPages).Msg("crawl pass skipped pages, misses are not counted")
+		} else {
+			s.delistUnseen(ctx, category, append(regions, mirrors...))
+		}
+	}
+
+	s.scheduleNextCrawl(ctx, category, time.Since(start), isThrottled, isPassFinished)
+
+	if err != nil && !isThrottled {
 		return err
 	}
 
-	if isUpdated {
+	if result.isUpdated {
 		if err = s.trackingNotifier.SendNotifications(ctx, category.ID); err != nil {
 			return err
 		}
+
+		// everything is new until the category was crawled through once, there is nothing to announce yet
+		if checkpoint.Passes > 0 && len(result.newProducts) > 0 {
+			productIDs := make([]uint64, 0, len(result.newProducts))
+			for _, product := range result.newProducts {
+				productIDs = append(productIDs, product.ID)
+			}
+
+			if err = s.trackingNotifier.SendNewArrivals(ctx, category.ID, productIDs); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
 }
+
+// crawlResult collects the pages of all regions, products are shared between regions and counted once.
+// skippedPages is set for a finished pass only.
+type crawlResult struct {
+	isUpdated    bool
+	pages        int
+	skippedPages uint
+	newProducts  []model.ProductRef
+}
+
+// crawl walks the regions from the checkpoint until the last page of every region, an error or the end of the page budget
+// and returns the checkpoint the next crawl resumes from.
+func (s *ProductService) crawl(
+	ctx context.Context,
+	category model.Category,
+	regions []model.Region,
+	mirrors []model.Region,
+	checkpoint model.CrawlCheckpoint,
+	result *crawlResult,
+) (model.CrawlCheckpoint, error) {
+	first, page := 0, 1
+	for i, region := range regions {
+		// a region without chats since the checkpoint is dropped and the pass goes on from the first region
+		if region.ID == checkpoint.RegionID {
+			first, page = i, max(checkpoint.Page, 1)
+			break
+		}
+	}
+
+	skipped := checkpoint.SkippedPages
+	for i := first; i < len(regions); i++ {
+		next, err := s.crawlRegion(ctx, category, regions[i], mirrors, page, result)
+		if errors.Is(err, model.ErrMalformedPage) {
+			// a broken page must not hold the whole category back, the next crawl goes on after it.
+			// Other errors say nothing about the page, it is crawled again.
+			next++
+			skipped++
+		}
+
+		if err != nil || next > 0 {
+			return model.CrawlCheckpoint{RegionID: regions[i].ID, Page: next, Passes: checkpoint.Passes, SkippedPages: skipped}, err
+		}
+
+		page = 1
+	}
+
+	result.skippedPages = skipped
+	return model.CrawlCheckpoint{Page: 1, Passes: checkpoint.Passes + 1}, nil
+}
+
+// crawlRegion returns the page to resume from, 0 when the last page of the region was reached.
+// The products of the region are saved for the mirror regions too.
+func (s *ProductService) crawlRegion(
+	ctx context.Context,
+	category model.Category,
+	region model.Region,
+	mirrors []model.Region,
+	page int,
+	result *crawlResult,
+) (int, error) {
+	request := model.ProductsRequest{
+		Page:        page,
+		Type:        category.Type,
+		Marketplace: category.Marketplace,
+		Category:    category.Name,
+		CategoryID:  category.ID,
+		RegionID:    region.ID,
+		Dest:        region.Dest,
+		RequestURL:  category.RequestURL,
+		ProductURL:  category.ProductURL,
+		Query:       category.Query,
+	}
+
+	for {
+		if s.config.MaxPages > 0 && result.pages >= s.config.MaxPages {
+			return request.Page, nil
+		}
+
+		s.logger.Debug().Str("category", category.Name).Int64("dest", region.Dest).Int("page", request.Page).Msg("products request")
+
+		count, err := s.updatePage(ctx, request, mirrors, result)
+		if err != nil {
+			return request.Page, err
+		}
+
+		if count == 0 {
+			return 0, nil
+		}
+
+		result.pages++
+		request.Page++
+	}
+}
+
+// updatePage saves the products of the page in chunks of ChunkSize as they are decoded, 0 saves the page at once.
+// A page failed in the middle keeps the chunks saved before the failure, upserts make its retry safe.
+func (s *ProductService) updatePage(ctx context.Context, request model.ProductsRequest, mirrors []model.Region, result *crawlResult) (int, error) {
+	var count int
+	var chunk []model.Product
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		newProducts, err := s.productRepository.Update(ctx, chunk)
+		if err != nil {
+			return err
+		}
+
+		// the repository saves one region per call, products of the mirrors are not new anymore
+		for _, mirror := range mirrors {
+			mirrored := make([]model.Product, 0, len(chunk))
+			for _, product := range chunk {
+				product.RegionID = mirror.ID
+				mirrored = append(mirrored, product)
+			}
+
+			if _, err = s.productRepository.Update(ctx, mirrored); err != nil {
+				return err
+			}
+		}
+
+		result.isUpdated = true
+		result.newProducts = append(result.newProducts, newProducts...)
+		chunk = chunk[:0]
+
+		return nil
+	}
+
+	err := s.client.StreamProducts(ctx, request, func(product model.Product) error {
+		count++
+		chunk = append(chunk, product)
+		if s.config.ChunkSize > 0 && len(chunk) >= s.config.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
+
+// splitDefaultRegion returns the default region alone and the other regions, all regions are kept without a default one.
+func splitDefaultRegion(regions []model.Region) ([]model.Region, []model.Region) {
+	for i, region := range regions {
+		if region.IsDefault {
+			mirrors := make([]model.Region, 0, len(regions)-1)
+			mirrors = append(mirrors, regions[:i]...)
+			mirrors = append(mirrors, regions[i+1:]...)
+			return []model.Region{region}, mirrors
+		}
+	}
+
+	return regions, nil
+}
+
+func (s *ProductService) startCrawl(categoryID uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.crawling[categoryID]; ok {
+		return false
+	}
+
+	s.crawling[categoryID] = struct{}{}
+	return true
+}
+
+func (s *ProductService) finishCrawl(categoryID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.crawling, categoryID)
+}
+
+// pushEnrichment does not wait for the enrichment queue, products are dropped when it is full
+// so a slow card api never holds up crawls and shutdown.
+func (s *ProductService) pushEnrichment(ctx context.Context, category model.Category, products []model.ProductRef) {
+	for i, product := range products {
+		err := s.enrichmentQueue.Push(ctx, product)
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, queue.ErrQueueFull) {
+			s.logger.Warn().Str("category", category.Name).Int("dropped", len(products)-i).Msg("enrichment queue is full")
+			return
+		}
+
+		s.logger.Error().Err(err).Uint64("product_id", product.ID).Msg("failed push product to enrichment")
+		return
+	}
+}
+
+// delistUnseen runs after a finished pass only, products beyond the page budget are not missing until the pass reaches them.
+// Sizes are only missing in the regions the pass was saved for.
+func (s *ProductService) delistUnseen(ctx context.Context, category model.Category, regions []model.Region) {
+	regionIDs := make([]uint64, 0, len(regions))
+	for _, region := range regions {
+		regionIDs = append(regionIDs, region.ID)
+	}
+
+	delisted, err := s.productRepository.DelistUnseen(ctx, category.ID, regionIDs, s.config.DelistAfter)
+	if err != nil {
+		s.logger.Error().Err(err).Str("category", category.Name).Msg("failed delist unseen products")
+		return
+	}
+
+	if delisted > 0 {
+		s.logger.Info().Str("category", category.Name).Int64("delisted", delisted).Msg("products delisted")
+	}
+}
+
+func (s *ProductService) scheduleNextCrawl(ctx context.Context, category model.Category, crawlDuration time.Duration, isThrottled bool, isPassFinished bool) {
+	stats, err := s.categoryRepository.GetCrawlStats(ctx, category.ID, crawlDuration)
+	if err != nil {
+		s.logger.Error().Err(err).Str("category", category.Name).Msg("failed get category crawl stats")
+	}
+
+	if isThrottled {
+		stats.ThrottleCount++
+	} else {
+		stats.ThrottleCount = 0
+	}
+
+	nextInterval := s.scheduler.NextInterval(stats)
+	if !isPassFinished && !isThrottled {
+		// the page budget ran out, the rest of the category is crawled as soon as possible
+		nextInterval = min(nextInterval, s.scheduler.ResumeInterval())
+	}
+
+	s.logger.Info().
+		Str("category", category.Name).
+		Uint("trackings", stats.TrackingsCount).
+		Uint("sizes", stats.SizesCount).
+		Uint("changed_sizes", stats.ChangedSizesCount).
+		Uint("throttle_count", stats.ThrottleCount).
+		Bool("pass_finished", isPassFinished).
+		Str("next_interval", nextInterval.String()).
+		Msg("category crawl finished")
+
+	if err = s.categoryRepository.UpdateCrawlSchedule(ctx, category.ID, nextInterval, stats.ThrottleCount); err != nil {
+		s.logger.Error().Err(err).Str("category", category.Name).Msg("failed update category crawl schedule")
+	}
+}
@@ -0,0 +1,1073 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/fakeozon"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/fakewb"
+	httptransport "github.com/iamsorryprincess/wildberries-bot/cmd/api/http"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/repository"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql/mysqltest"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const testCategory = "dresses"
+
+type memorySender struct {
+	mu          sync.Mutex
+	results     []model.TrackingResult
+	newArrivals []model.NewArrivalsMessage
+	lowStock    []model.LowStockResult
+	backInStock []model.StockResult
+}
+
+func (s *memorySender) Send(_ context.Context, message model.TrackingResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, message)
+	return nil
+}
+
+func (s *memorySender) SendBackInStock(_ context.Context, message model.StockResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.backInStock = append(s.backInStock, message)
+	return nil
+}
+
+func (s *memorySender) SendLowStock(_ context.Context, message model.LowStockResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lowStock = append(s.lowStock, message)
+	return nil
+}
+
+func (s *memorySender) SendNewArrivals(_ context.Context, message model.NewArrivalsMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.newArrivals = append(s.newArrivals, message)
+	return nil
+}
+
+type memoryQueue struct {
+	mu       sync.Mutex
+	messages []model.ProductRef
+}
+
+func (q *memoryQueue) Push(_ context.Context, message model.ProductRef) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.messages = append(q.messages, message)
+	return nil
+}
+
+// serviceFixture runs the product and tracking services on the mysql repositories of a database of its own,
+// products are served by the fake catalog and notifications are collected by the memory sender.
+type serviceFixture struct {
+	server   *fakewb.Server
+	conn     *mysql.Connection
+	category model.Category
+	sender   *memorySender
+	queue    *memoryQueue
+	service  *ProductService
+
+	categoryRepository *repository.MysqlCategoryRepository
+	trackingRepository *repository.MysqlTrackingRepository
+	stockRepository    *repository.MysqlStockRepository
+	regionRepository   *repository.MysqlRegionRepository
+	chatRepository     *repository.MysqlChatRepository
+}
+
+func newServiceFixture(t *testing.T) *serviceFixture {
+	t.Helper()
+
+	conn := mysqltest.New(t)
+	logger := log.NewNop()
+
+	server := fakewb.NewServer()
+	t.Cleanup(server.Close)
+
+	if err := server.LoadFixture(testCategory, 1, "dresses-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.LoadFixture(testCategory, 2, "dresses-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &memorySender{}
+	enrichmentQueue := &memoryQueue{}
+
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	client := httptransport.NewProductClient(logger, httptransport.ProductClientConfig{}, httpClient)
+	ozonClient := httptransport.NewOzonClient(logger, httptransport.OzonClientConfig{}, httpClient)
+
+	marketplaces := NewMarketplaceRegistry()
+	marketplaces.Register(model.MarketplaceWildberries, client, nil, "wildberries.ru")
+	marketplaces.Register(model.MarketplaceOzon, ozonClient, ozonClient, "ozon.ru")
+
+	fixture := &serviceFixture{
+		server: server,
+		conn:   conn,
+		sender: sender,
+		queue:  enrichmentQueue,
+
+		categoryRepository: repository.NewMysqlCategoryRepository(logger, conn),
+		trackingRepository: repository.NewMysqlTrackingRepository(logger, conn),
+		stockRepository:    repository.NewMysqlStockRepository(conn),
+		regionRepository:   repository.NewMysqlRegionRepository(conn),
+		chatRepository:     repository.NewMysqlChatRepository(conn),
+	}
+
+	scheduler := NewCrawlScheduler(SchedulerConfig{MinInterval: time.Minute, MaxInterval: time.Hour})
+	trackingService := NewTrackingService(logger, fixture.trackingRepository, fixture.stockRepository, sender)
+
+	fixture.service = NewProductService(
+		logger,
+		ProductServiceConfig{Concurrency: 1, ChunkSize: 1},
+		marketplaces,
+		scheduler,
+		fixture.categoryRepository,
+		repository.NewMysqlProductRepository(logger, conn),
+		fixture.regionRepository,
+		trackingService,
+		enrichmentQueue,
+	)
+
+	fixture.category = fixture.addCategory(t, model.Category{
+		Marketplace: model.MarketplaceWildberries,
+		Name:        "test_" + testCategory,
+		Title:       "Платья",
+		Emoji:       "👗",
+		RequestURL:  server.RequestURL(testCategory),
+		ProductURL:  server.ProductURL(),
+	})
+
+	return fixture
+}
+
+func (f *serviceFixture) crawl(t *testing.T) error {
+	t.Helper()
+	return f.service.UpdateProducts(context.Background(), f.category)
+}
+
+// crawlPass starts the crawl a second after the previous one, passes are told apart by second precision timestamps.
+func (f *serviceFixture) crawlPass(t *testing.T) error {
+	t.Helper()
+	time.Sleep(time.Second)
+	return f.crawl(t)
+}
+
+func (f *serviceFixture) addCategory(t *testing.T, category model.Category) model.Category {
+	t.Helper()
+
+	id, err := f.categoryRepository.AddCategory(context.Background(), category)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	category.ID = id
+	return category
+}
+
+func (f *serviceFixture) addTracking(t *testing.T, settings model.TrackingSettings, size string) {
+	t.Helper()
+
+	if err := f.chatRepository.SaveChat(context.Background(), settings.ChatID); err != nil {
+		t.Fatal(err)
+	}
+
+	settings.SizeID = f.sizeID(t, size)
+	if settings.CategoryID == 0 {
+		settings.CategoryID = f.category.ID
+	}
+
+	if err := f.trackingRepository.AddTracking(context.Background(), settings); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *serviceFixture) setChatRegion(t *testing.T, chatID int64, region model.Region) {
+	t.Helper()
+
+	regionID, err := f.regionRepository.AddRegion(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = f.regionRepository.SetChatRegion(context.Background(), chatID, regionID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sizeID returns the id of the size, sizes are created by crawls and trackings can be added before them.
+func (f *serviceFixture) sizeID(t *testing.T, name string) uint64 {
+	t.Helper()
+
+	f.exec(t, "insert into sizes (name, created_at) values (?, NOW()) on duplicate key update updated_at = NOW();", name)
+
+	var id uint64
+	if err := f.conn.QueryRow("select id from sizes where name = ?;", name).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func (f *serviceFixture) exec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+
+	if _, err := f.conn.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *serviceFixture) queryIDs(t *testing.T, query string, args ...interface{}) []uint64 {
+	t.Helper()
+
+	rows, err := f.conn.Query(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.conn.CloseRows(rows)
+
+	var result []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+// productID returns the id a wildberries article is saved under, the helpers below return articles.
+func (f *serviceFixture) productID(t *testing.T, article uint64) uint64 {
+	t.Helper()
+
+	var id uint64
+	if err := f.conn.QueryRow("select id from products where marketplace = ? and external_id = ?;", model.MarketplaceWildberries, article).Scan(&id); err != nil {
+		t.Fatalf("product %d: %v", article, err)
+	}
+
+	return id
+}
+
+func (f *serviceFixture) productIDs(t *testing.T) []uint64 {
+	t.Helper()
+	return f.queryIDs(t, "select external_id from products order by external_id;")
+}
+
+func (f *serviceFixture) delistedProductIDs(t *testing.T) []uint64 {
+	t.Helper()
+	return f.queryIDs(t, "select external_id from products where delisted_at is not null order by external_id;")
+}
+
+// delistedSizeProductIDs returns the products with delisted sizes, a delisted size is never available.
+func (f *serviceFixture) delistedSizeProductIDs(t *testing.T) []uint64 {
+	t.Helper()
+
+	const availableQuery = `select p.external_id from products_sizes as ps join products as p on p.id = ps.product_id
+where ps.is_delisted = 1 and ps.is_available = 1;`
+	if available := f.queryIDs(t, availableQuery); len(available) > 0 {
+		t.Errorf("delisted sizes are available for products %v", available)
+	}
+
+	const delistedQuery = `select distinct p.external_id from products_sizes as ps join products as p on p.id = ps.product_id
+where ps.is_delisted = 1 order by p.external_id;`
+	return f.queryIDs(t, delistedQuery)
+}
+
+func (f *serviceFixture) throttleCount(t *testing.T) uint {
+	t.Helper()
+
+	var count uint
+	if err := f.conn.QueryRow("select throttle_count from categories where id = ?;", f.category.ID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+
+	return count
+}
+
+func (f *serviceFixture) checkpoint(t *testing.T) model.CrawlCheckpoint {
+	t.Helper()
+
+	checkpoint, err := f.categoryRepository.GetCrawlCheckpoint(context.Background(), f.category.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return checkpoint
+}
+
+func TestUpdateProductsNotifiesPriceDrop(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if products := fixture.productIDs(t); len(products) != 3 {
+		t.Fatalf("expected 3 products, got %v", products)
+	}
+
+	if len(fixture.sender.results) != 0 {
+		t.Fatalf("expected no notifications after first crawl, got %d", len(fixture.sender.results))
+	}
+
+	fixture.server.SetPrice(testCategory, 1001, "42", 299000)
+	fixture.server.SetPrice(testCategory, 1002, "42", 600000)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(fixture.sender.results))
+	}
+
+	result := fixture.sender.results[0]
+	if result.ChatID != 100 || result.ProductID != fixture.productID(t, 1001) || result.Size != "42" {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+
+	if result.PreviousPrice != 350000 || result.CurrentPrice != 299000 || result.DiffPercent != 15 {
+		t.Errorf("unexpected notification prices: %+v", result)
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("third crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Errorf("expected no repeated notifications, got %d", len(fixture.sender.results))
+	}
+}
+
+func TestUpdateProductsKeepsPriceDropForOtherSources(t *testing.T) {
+	fixture := newServiceFixture(t)
+
+	if err := fixture.server.LoadFixture("dresses-sale", 1, "dresses-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	sale := fixture.addCategory(t, model.Category{
+		Marketplace: model.MarketplaceWildberries,
+		Name:        "test_dresses_sale",
+		Title:       "Платья со скидкой",
+		Emoji:       "👗",
+		RequestURL:  fixture.server.RequestURL("dresses-sale"),
+		ProductURL:  fixture.server.ProductURL(),
+	})
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10, CategoryID: sale.ID}, "44")
+
+	crawlSale := func() {
+		t.Helper()
+		if err := fixture.service.UpdateProducts(context.Background(), sale); err != nil {
+			t.Fatalf("sale crawl failed: %v", err)
+		}
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+	crawlSale()
+
+	// the catalog section crawls the drop first, nobody tracks it there
+	fixture.server.SetPrice(testCategory, 1003, "44", 159000)
+	fixture.server.SetPrice("dresses-sale", 1003, "44", 159000)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+	crawlSale()
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected the drop seen by the tracked source, got %+v", fixture.sender.results)
+	}
+
+	if result := fixture.sender.results[0]; result.ChatID != 100 || result.PreviousPrice != 199000 || result.CurrentPrice != 159000 {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+
+	crawlSale()
+
+	if len(fixture.sender.results) != 1 {
+		t.Errorf("expected one notification for one drop, got %+v", fixture.sender.results)
+	}
+}
+
+func TestUpdateProductsIgnoresDropsBeforeTracking(t *testing.T) {
+	fixture := newServiceFixture(t)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	fixture.server.SetPrice(testCategory, 1001, "42", 299000)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	// the chat starts tracking after the drop, the price it sees first is the baseline
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("third crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 0 {
+		t.Fatalf("expected no notification about the drop before tracking, got %+v", fixture.sender.results)
+	}
+
+	fixture.server.SetPrice(testCategory, 1001, "42", 259000)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("fourth crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected the next drop notified, got %+v", fixture.sender.results)
+	}
+
+	if result := fixture.sender.results[0]; result.PreviousPrice != 299000 || result.CurrentPrice != 259000 || result.DiffPercent != 13 {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+}
+
+func TestUpdateProductsNotifiesProductPriceDrop(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "44")
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 200, Type: model.TrackingTypePriceDrop, DiffValue: 10, PriceType: model.PriceTypeProduct}, "44")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	// seller discount grows while the logistics surcharge keeps the total almost unchanged
+	fixture.server.SetPriceComponents(testCategory, 1001, "44", fakewb.Price{
+		Basic:     500000,
+		Product:   300000,
+		Total:     340000,
+		Logistics: 40000,
+	})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected only the product price tracking to match, got %+v", fixture.sender.results)
+	}
+
+	result := fixture.sender.results[0]
+	if result.ChatID != 200 || result.PriceType != model.PriceTypeProduct || result.PreviousPrice != 350000 || result.CurrentPrice != 300000 {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+
+	if result.BasicPrice != 500000 || result.LogisticsPrice != 40000 || result.DiffPercent != 14 {
+		t.Errorf("unexpected notification price components: %+v", result)
+	}
+}
+
+func TestUpdateProductsMatchesRegionPrices(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 200, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+	fixture.setChatRegion(t, 200, model.Region{Name: "Казань", Dest: -2133462})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if requests := fixture.server.Requests(testCategory, 1); requests != 2 {
+		t.Fatalf("expected page 1 to be crawled once per region, got %d requests", requests)
+	}
+
+	fixture.server.SetRegionPrice(-2133462, 1001, "42", 299000)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected only the chat of the region with the price drop to be notified, got %+v", fixture.sender.results)
+	}
+
+	if result := fixture.sender.results[0]; result.ChatID != 200 || result.PreviousPrice != 350000 || result.CurrentPrice != 299000 {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+}
+
+func TestUpdateProductsThrottled(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if count := fixture.throttleCount(t); count != 0 {
+		t.Errorf("unexpected throttle count after first crawl: %d", count)
+	}
+
+	fixture.server.SetPrice(testCategory, 1001, "42", 299000)
+	fixture.server.Fail(testCategory, 2, fakewb.FaultTooManyRequests)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("throttled crawl must not fail: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Errorf("expected notification for crawled pages, got %d", len(fixture.sender.results))
+	}
+
+	if count := fixture.throttleCount(t); count != 1 {
+		t.Errorf("unexpected throttle count after throttled crawl: %d", count)
+	}
+}
+
+func TestUpdateProductsResumesFromCheckpoint(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.service.config.MaxPages = 1
+
+	expected := []struct {
+		products   int
+		checkpoint model.CrawlCheckpoint
+	}{
+		{products: 2, checkpoint: model.CrawlCheckpoint{RegionID: 1, Page: 2}},
+		{products: 3, checkpoint: model.CrawlCheckpoint{RegionID: 1, Page: 3}},
+		{products: 3, checkpoint: model.CrawlCheckpoint{Page: 1, Passes: 1}},
+	}
+
+	for i, step := range expected {
+		if err := fixture.crawl(t); err != nil {
+			t.Fatalf("crawl %d failed: %v", i+1, err)
+		}
+
+		if products := fixture.productIDs(t); len(products) != step.products {
+			t.Errorf("crawl %d: expected %d products, got %v", i+1, step.products, products)
+		}
+
+		if checkpoint := fixture.checkpoint(t); checkpoint != step.checkpoint {
+			t.Errorf("crawl %d: expected checkpoint %+v, got %+v", i+1, step.checkpoint, checkpoint)
+		}
+	}
+
+	if requests := fixture.server.Requests(testCategory, 1); requests != 1 {
+		t.Errorf("expected first page crawled once per pass, got %d", requests)
+	}
+}
+
+func TestUpdateProductsDelistsMissingProducts(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.service.config.DelistAfter = 2
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 100, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "42")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	fixture.server.SetPage(testCategory, 2, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := fixture.crawlPass(t); err != nil {
+			t.Fatalf("crawl without product failed: %v", err)
+		}
+	}
+
+	if delisted := fixture.delistedProductIDs(t); !slices.Equal(delisted, []uint64{1003}) {
+		t.Fatalf("expected only product 1003 delisted, got %v", delisted)
+	}
+
+	if delisted := fixture.delistedSizeProductIDs(t); !slices.Equal(delisted, []uint64{1003}) {
+		t.Errorf("expected only the sizes of product 1003 delisted, got %v", delisted)
+	}
+
+	if err := fixture.server.LoadFixture(testCategory, 2, "dresses-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fixture.crawlPass(t); err != nil {
+		t.Fatalf("crawl with returned product failed: %v", err)
+	}
+
+	if delisted := fixture.delistedProductIDs(t); len(delisted) != 0 {
+		t.Errorf("expected returned product listed back, got %v", delisted)
+	}
+
+	if len(fixture.sender.newArrivals) != 0 {
+		t.Errorf("returned product must not be announced as new, got %d", len(fixture.sender.newArrivals))
+	}
+}
+
+func TestUpdateProductsDelistsProductsMissedByEverySource(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.service.config.DelistAfter = 2
+
+	if err := fixture.server.LoadFixture("dresses-sale", 1, "dresses-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	sale := fixture.addCategory(t, model.Category{
+		Marketplace: model.MarketplaceWildberries,
+		Name:        "test_dresses_sale",
+		Title:       "Платья со скидкой",
+		Emoji:       "👗",
+		RequestURL:  fixture.server.RequestURL("dresses-sale"),
+		ProductURL:  fixture.server.ProductURL(),
+	})
+
+	crawlSale := func() {
+		t.Helper()
+		time.Sleep(time.Second)
+		if err := fixture.service.UpdateProducts(context.Background(), sale); err != nil {
+			t.Fatalf("sale crawl failed: %v", err)
+		}
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+	crawlSale()
+
+	// the catalog section drops the product, the sale still lists it
+	fixture.server.SetPage(testCategory, 2, nil)
+	for i := 0; i < 3; i++ {
+		if err := fixture.crawlPass(t); err != nil {
+			t.Fatalf("crawl without product failed: %v", err)
+		}
+	}
+
+	if delisted := fixture.delistedProductIDs(t); len(delisted) != 0 {
+		t.Fatalf("expected product listed by another source kept, got %v", delisted)
+	}
+
+	if delisted := fixture.delistedSizeProductIDs(t); len(delisted) != 0 {
+		t.Errorf("expected sizes of listed products kept, got %v", delisted)
+	}
+
+	fixture.server.SetPage("dresses-sale", 1, nil)
+	crawlSale()
+	crawlSale()
+
+	if delisted := fixture.delistedProductIDs(t); !slices.Equal(delisted, []uint64{1003}) {
+		t.Fatalf("expected product 1003 delisted once missed everywhere, got %v", delisted)
+	}
+
+	if delisted := fixture.delistedSizeProductIDs(t); !slices.Equal(delisted, []uint64{1003}) {
+		t.Errorf("expected only the sizes of product 1003 delisted, got %v", delisted)
+	}
+}
+
+func TestUpdateProductsCrawlsCategoryMarketplace(t *testing.T) {
+	fixture := newServiceFixture(t)
+
+	server := fakeozon.NewServer()
+	t.Cleanup(server.Close)
+
+	server.SetPage("platya-7502", 1, []fakeozon.Item{
+		{SKU: 501, Name: "Платье миди", Price: "1 299 ₽", Stock: 4},
+		{SKU: 502, Name: "Платье макси", Price: "2 499 ₽", Stock: 1},
+	})
+
+	fixture.category = fixture.addCategory(t, model.Category{
+		Marketplace: model.MarketplaceOzon,
+		Name:        "o_7502",
+		Title:       "Платья",
+		Emoji:       "👗",
+		RequestURL:  server.RequestURL("platya-7502"),
+		ProductURL:  server.ProductURL(),
+	})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	if fixture.server.Requests(testCategory, 1) != 0 || server.Requests("platya-7502", 1) != 1 {
+		t.Fatalf("expected the category crawled through the ozon adapter only")
+	}
+
+	ozonProducts := fixture.queryIDs(t, "select external_id from products where marketplace = ? order by external_id;", model.MarketplaceOzon)
+	if products := fixture.productIDs(t); !slices.Equal(products, []uint64{501, 502}) || !slices.Equal(products, ozonProducts) {
+		t.Errorf("unexpected products: %v", products)
+	}
+
+	if len(fixture.queue.messages) != 0 {
+		t.Errorf("ozon products must not be enriched, got %v", fixture.queue.messages)
+	}
+
+	fixture.category.Marketplace = "unknown"
+	if err := fixture.crawl(t); !errors.Is(err, model.ErrUnknownMarketplace) {
+		t.Errorf("expected unknown marketplace error, got %v", err)
+	}
+}
+
+func TestUpdateProductsCrawlsDefaultRegionWithoutRegionPrices(t *testing.T) {
+	fixture := newServiceFixture(t)
+
+	server := fakeozon.NewServer()
+	t.Cleanup(server.Close)
+
+	server.SetPage("platya-7502", 1, []fakeozon.Item{{SKU: 501, Name: "Платье миди", Price: "2 000 ₽", Stock: 4}})
+
+	fixture.category = fixture.addCategory(t, model.Category{
+		Marketplace: model.MarketplaceOzon,
+		Name:        "o_7502",
+		Title:       "Платья",
+		Emoji:       "👗",
+		RequestURL:  server.RequestURL("platya-7502"),
+		ProductURL:  server.ProductURL(),
+	})
+
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 200, Type: model.TrackingTypePriceDrop, DiffValue: 10}, "0")
+	fixture.setChatRegion(t, 200, model.Region{Name: "Казань", Dest: -2133462})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if requests := server.Requests("platya-7502", 1); requests != 1 {
+		t.Fatalf("expected page 1 crawled once for all regions, got %d requests", requests)
+	}
+
+	server.SetPage("platya-7502", 1, []fakeozon.Item{{SKU: 501, Name: "Платье миди", Price: "1 500 ₽", Stock: 4}})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.results) != 1 {
+		t.Fatalf("expected the chat of the other region notified with the copied prices, got %+v", fixture.sender.results)
+	}
+
+	if result := fixture.sender.results[0]; result.ChatID != 200 || result.PreviousPrice != model.Rubles(2000) || result.CurrentPrice != model.Rubles(1500) {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+}
+
+func TestUpdateProductsFailedPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		fault      fakewb.Fault
+		products   int
+		isFailed   bool
+		checkpoint model.CrawlCheckpoint
+	}{
+		{name: "no content", fault: fakewb.FaultNoContent, products: 2, checkpoint: model.CrawlCheckpoint{Page: 1, Passes: 1}},
+		{
+			name:       "malformed json",
+			fault:      fakewb.FaultMalformedJSON,
+			products:   2,
+			isFailed:   true,
+			checkpoint: model.CrawlCheckpoint{RegionID: 1, Page: 3, SkippedPages: 1},
+		},
+		{
+			name:       "internal error",
+			fault:      fakewb.FaultInternalError,
+			products:   2,
+			isFailed:   true,
+			checkpoint: model.CrawlCheckpoint{RegionID: 1, Page: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixture := newServiceFixture(t)
+			fixture.server.Fail(testCategory, 2, tt.fault)
+
+			err := fixture.crawl(t)
+			if tt.isFailed != (err != nil) {
+				t.Fatalf("unexpected crawl error: %v", err)
+			}
+
+			if products := fixture.productIDs(t); len(products) != tt.products {
+				t.Errorf("expected %d products, got %v", tt.products, products)
+			}
+
+			if checkpoint := fixture.checkpoint(t); checkpoint != tt.checkpoint {
+				t.Errorf("expected checkpoint %+v, got %+v", tt.checkpoint, checkpoint)
+			}
+
+			if count := fixture.throttleCount(t); count != 0 {
+				t.Errorf("unexpected throttle count: %d", count)
+			}
+		})
+	}
+}
+
+// failingProductRepository fails saving the products of failIDs, the rest is saved by the wrapped repository.
+type failingProductRepository struct {
+	ProductUpdateRepository
+	failIDs map[uint64]struct{}
+}
+
+func (r *failingProductRepository) Update(ctx context.Context, products []model.Product) ([]model.ProductRef, error) {
+	for _, product := range products {
+		if _, ok := r.failIDs[product.ExternalID]; ok {
+			return nil, fmt.Errorf("mysql products repository: failed exec insert products: %w", context.DeadlineExceeded)
+		}
+	}
+
+	return r.ProductUpdateRepository.Update(ctx, products)
+}
+
+func TestUpdateProductsKeepsPageOnSaveError(t *testing.T) {
+	fixture := newServiceFixture(t)
+	repository := &failingProductRepository{
+		ProductUpdateRepository: fixture.service.productRepository,
+		failIDs:                 map[uint64]struct{}{1003: {}},
+	}
+	fixture.service.productRepository = repository
+
+	if err := fixture.crawl(t); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the save error, got %v", err)
+	}
+
+	if checkpoint, expected := fixture.checkpoint(t), (model.CrawlCheckpoint{RegionID: 1, Page: 2}); checkpoint != expected {
+		t.Fatalf("expected checkpoint %+v, got %+v", expected, checkpoint)
+	}
+
+	repository.failIDs = nil
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("crawl after the save error failed: %v", err)
+	}
+
+	if products := fixture.productIDs(t); !slices.Equal(products, []uint64{1001, 1002, 1003}) {
+		t.Errorf("expected the failed page saved by the next crawl, got %v", products)
+	}
+
+	if requests := fixture.server.Requests(testCategory, 2); requests != 2 {
+		t.Errorf("expected the failed page requested again, got %d requests", requests)
+	}
+}
+
+func TestUpdateProductsSkippedPageDoesNotCountMisses(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.service.config.DelistAfter = 1
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	fixture.server.Fail(testCategory, 2, fakewb.FaultMalformedJSON)
+	if err := fixture.crawlPass(t); !errors.Is(err, model.ErrMalformedPage) {
+		t.Fatalf("expected malformed page error, got %v", err)
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("crawl after the skipped page failed: %v", err)
+	}
+
+	if checkpoint, expected := fixture.checkpoint(t), (model.CrawlCheckpoint{Page: 1, Passes: 2}); checkpoint != expected {
+		t.Fatalf("expected a finished pass, got checkpoint %+v", checkpoint)
+	}
+
+	if delisted := fixture.delistedProductIDs(t); len(delisted) != 0 {
+		t.Errorf("expected products of the skipped page kept, got %v", delisted)
+	}
+
+	if missed := fixture.queryIDs(t, "select product_id from categories_products where missed_passes > 0;"); len(missed) != 0 {
+		t.Errorf("expected no misses counted for the pass, got %v", missed)
+	}
+
+	if err := fixture.crawlPass(t); err != nil {
+		t.Fatalf("full pass failed: %v", err)
+	}
+
+	if delisted := fixture.delistedProductIDs(t); len(delisted) != 0 {
+		t.Errorf("expected no products delisted after a full pass, got %v", delisted)
+	}
+}
+
+func TestUpdateProductsNotifiesNewArrivals(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 200, Type: model.TrackingTypeNewArrivals, MaxPrice: model.Rubles(3000)}, "44")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.newArrivals) != 0 {
+		t.Fatalf("expected no new arrivals after first crawl, got %d", len(fixture.sender.newArrivals))
+	}
+
+	fixture.server.SetPage(testCategory, 3, []fakewb.Product{
+		{
+			ID:    1004,
+			Name:  "Платье-комбинация",
+			Brand: "Befree",
+			Sizes: []fakewb.Size{
+				{Name: "44", Stocks: []fakewb.Stock{{Warehouse: 507, Quantity: 5}}, Price: fakewb.Price{Basic: 400000, Product: 259000, Total: 259000}},
+			},
+		},
+		{
+			ID:    1005,
+			Name:  "Платье вечернее",
+			Brand: "Zarina",
+			Sizes: []fakewb.Size{
+				{Name: "44", Stocks: []fakewb.Stock{{Warehouse: 507, Quantity: 5}}, Price: fakewb.Price{Basic: 900000, Product: 790000, Total: 790000}},
+			},
+		},
+	})
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if queued := fixture.queue.messages; len(queued) != 5 || queued[3].ExternalID != 1004 || queued[4].ExternalID != 1005 {
+		t.Errorf("expected new products queued for enrichment, got %v", queued)
+	}
+
+	if len(fixture.sender.newArrivals) != 1 {
+		t.Fatalf("expected 1 new arrivals message, got %d", len(fixture.sender.newArrivals))
+	}
+
+	message := fixture.sender.newArrivals[0]
+	if message.ChatID != 200 || message.CategoryTitle != "Платья" || len(message.Items) != 1 {
+		t.Fatalf("unexpected new arrivals message: %+v", message)
+	}
+
+	if item := message.Items[0]; item.ProductID != fixture.productID(t, 1004) || item.CurrentPrice != 259000 {
+		t.Errorf("unexpected new arrival: %+v", item)
+	}
+}
+
+func TestUpdateProductsNotifiesLowStock(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.addTracking(t, model.TrackingSettings{ChatID: 300, Type: model.TrackingTypeLowStock, MaxPrice: model.Rubles(4000), StockThreshold: 5}, "42")
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.lowStock) != 0 {
+		t.Fatalf("expected no low stock alerts after first crawl, got %d", len(fixture.sender.lowStock))
+	}
+
+	fixture.server.SetQuantity(testCategory, 1001, "42", 3)
+	fixture.server.SetQuantity(testCategory, 1002, "42", 1)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("second crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.lowStock) != 1 {
+		t.Fatalf("expected 1 low stock alert, got %d", len(fixture.sender.lowStock))
+	}
+
+	if alert := fixture.sender.lowStock[0]; alert.ChatID != 300 || alert.ProductID != fixture.productID(t, 1001) || alert.Quantity != 3 || alert.CurrentPrice != 350000 {
+		t.Errorf("unexpected low stock alert: %+v", alert)
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("third crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.lowStock) != 1 {
+		t.Fatalf("expected no repeated alerts, got %d", len(fixture.sender.lowStock))
+	}
+
+	fixture.server.SetQuantity(testCategory, 1001, "42", 20)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("restock crawl failed: %v", err)
+	}
+
+	fixture.server.SetQuantity(testCategory, 1001, "42", 2)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("last crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.lowStock) != 2 || fixture.sender.lowStock[1].Quantity != 2 {
+		t.Errorf("expected alert after restock and new drop, got %+v", fixture.sender.lowStock)
+	}
+}
+
+func TestUpdateProductsNotifiesBackInStock(t *testing.T) {
+	fixture := newServiceFixture(t)
+	fixture.server.SetQuantity(testCategory, 1001, "44", 0)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("first crawl failed: %v", err)
+	}
+
+	if err := fixture.chatRepository.SaveChat(context.Background(), 100); err != nil {
+		t.Fatal(err)
+	}
+
+	subscription := model.StockSubscription{ChatID: 100, ProductID: fixture.productID(t, 1001), SizeID: fixture.sizeID(t, "44")}
+	if err := fixture.stockRepository.AddSubscription(context.Background(), subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("sold out crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.backInStock) != 0 {
+		t.Fatalf("expected no notifications while the size is sold out, got %+v", fixture.sender.backInStock)
+	}
+
+	fixture.server.SetQuantity(testCategory, 1001, "44", 3)
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("restock crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.backInStock) != 1 {
+		t.Fatalf("expected 1 back in stock notification, got %+v", fixture.sender.backInStock)
+	}
+
+	if result := fixture.sender.backInStock[0]; result.ChatID != 100 || result.ProductID != fixture.productID(t, 1001) || result.Size != "44" || result.CurrentPrice != 350000 {
+		t.Errorf("unexpected notification: %+v", result)
+	}
+
+	if subscriptions := fixture.queryIDs(t, "select product_id from stock_subscriptions;"); len(subscriptions) != 0 {
+		t.Errorf("expected the subscription deleted once notified, got %v", subscriptions)
+	}
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("last crawl failed: %v", err)
+	}
+
+	if len(fixture.sender.backInStock) != 1 {
+		t.Errorf("expected no repeated notifications, got %+v", fixture.sender.backInStock)
+	}
+}
+
+func TestUpdateProductsSkipsCategoryBeingCrawled(t *testing.T) {
+	fixture := newServiceFixture(t)
+
+	// a crawl of the pool is still running when the admin starts the same category
+	fixture.service.startCrawl(fixture.category.ID)
+
+	if err := fixture.crawl(t); !errors.Is(err, model.ErrCrawlInProgress) {
+		t.Fatalf("expected crawl in progress, got %v", err)
+	}
+
+	if requests := fixture.server.Requests(testCategory, 1); requests != 0 {
+		t.Errorf("expected no requests while the category is crawled, got %d", requests)
+	}
+
+	fixture.service.finishCrawl(fixture.category.ID)
+
+	if err := fixture.crawl(t); err != nil {
+		t.Fatalf("crawl after the running one failed: %v", err)
+	}
+
+	if products := fixture.productIDs(t); len(products) != 3 {
+		t.Errorf("expected 3 products, got %v", products)
+	}
+}
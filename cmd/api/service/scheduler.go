@@ -0,0 +1,72 @@
+package service
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+)
+
+const (
+	maxThrottleShift = 4
+	volatilityWeight = 10
+)
+
+type SchedulerConfig struct {
+	MinInterval  time.Duration `config:"min_interval"`
+	MaxInterval  time.Duration `config:"max_interval"`
+	IdleInterval time.Duration `config:"idle_interval"`
+	Jitter       float64       `config:"jitter"`
+}
+
+type CrawlScheduler struct {
+	config SchedulerConfig
+}
+
+func NewCrawlScheduler(config SchedulerConfig) *CrawlScheduler {
+	return &CrawlScheduler{
+		config: config,
+	}
+}
+
+func (s *CrawlScheduler) CrawlIdle() bool {
+	return s.config.IdleInterval > 0
+}
+
+// NextInterval shortens the interval for categories with many trackers and frequent price changes
+// and backs off exponentially after crawls that ended with request limit.
+func (s *CrawlScheduler) NextInterval(stats model.CategoryCrawlStats) time.Duration {
+	if stats.TrackingsCount == 0 {
+		if s.CrawlIdle() {
+			return s.jitter(float64(s.config.IdleInterval))
+		}
+		return s.jitter(float64(s.config.MaxInterval))
+	}
+
+	interval := float64(s.config.MaxInterval)
+	interval /= 1 + math.Log2(1+float64(stats.TrackingsCount))
+
+	if stats.SizesCount > 0 {
+		interval /= 1 + volatilityWeight*float64(stats.ChangedSizesCount)/float64(stats.SizesCount)
+	}
+
+	interval = math.Max(interval, float64(s.config.MinInterval))
+	interval *= math.Pow(2, float64(min(stats.ThrottleCount, maxThrottleShift)))
+	interval = math.Min(interval, float64(s.config.MaxInterval))
+
+	return s.jitter(interval)
+}
+
+// ResumeInterval is the interval before a crawl that continues an unfinished pass.
+func (s *CrawlScheduler) ResumeInterval() time.Duration {
+	return s.jitter(float64(s.config.MinInterval))
+}
+
+func (s *CrawlScheduler) jitter(interval float64) time.Duration {
+	if s.config.Jitter <= 0 {
+		return time.Duration(interval)
+	}
+
+	return time.Duration(interval * (1 + s.config.Jitter*(2*rand.Float64()-1)))
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+)
+
+func TestCrawlSchedulerNextInterval(t *testing.T) {
+	config := SchedulerConfig{
+		MinInterval: time.Minute,
+		MaxInterval: time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		idle     time.Duration
+		stats    model.CategoryCrawlStats
+		expected time.Duration
+	}{
+		{name: "no trackings", expected: time.Hour},
+		{name: "no trackings with idle interval", idle: 6 * time.Hour, expected: 6 * time.Hour},
+		{name: "one tracking", stats: model.CategoryCrawlStats{TrackingsCount: 1}, expected: 30 * time.Minute},
+		{name: "more trackings", stats: model.CategoryCrawlStats{TrackingsCount: 3}, expected: 20 * time.Minute},
+		{name: "demand is logarithmic", stats: model.CategoryCrawlStats{TrackingsCount: 15}, expected: 12 * time.Minute},
+		{
+			name:     "volatile category",
+			stats:    model.CategoryCrawlStats{TrackingsCount: 15, SizesCount: 100, ChangedSizesCount: 10},
+			expected: 6 * time.Minute,
+		},
+		{
+			name:     "every size changed",
+			stats:    model.CategoryCrawlStats{TrackingsCount: 3, SizesCount: 10, ChangedSizesCount: 10},
+			expected: 20 * time.Minute / 11,
+		},
+		{
+			name:     "not below min interval",
+			stats:    model.CategoryCrawlStats{TrackingsCount: 255, SizesCount: 10, ChangedSizesCount: 10},
+			expected: time.Minute,
+		},
+		{name: "throttled once", stats: model.CategoryCrawlStats{TrackingsCount: 3, ThrottleCount: 1}, expected: 40 * time.Minute},
+		{name: "throttling not above max interval", stats: model.CategoryCrawlStats{TrackingsCount: 3, ThrottleCount: 2}, expected: time.Hour},
+		{
+			name:     "throttling backs off from min interval",
+			stats:    model.CategoryCrawlStats{TrackingsCount: 255, SizesCount: 10, ChangedSizesCount: 10, ThrottleCount: 2},
+			expected: 4 * time.Minute,
+		},
+		{
+			name:     "throttle shift is capped",
+			stats:    model.CategoryCrawlStats{TrackingsCount: 255, SizesCount: 10, ChangedSizesCount: 10, ThrottleCount: 10},
+			expected: 16 * time.Minute,
+		},
+		{
+			name:     "idle interval ignored with trackings",
+			idle:     6 * time.Hour,
+			stats:    model.CategoryCrawlStats{TrackingsCount: 1},
+			expected: 30 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.IdleInterval = tt.idle
+			scheduler := NewCrawlScheduler(config)
+
+			if actual := scheduler.NextInterval(tt.stats); (actual - tt.expected).Abs() > time.Microsecond {
+				t.Errorf("expected %s, got %s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCrawlSchedulerJitter(t *testing.T) {
+	scheduler := NewCrawlScheduler(SchedulerConfig{
+		MinInterval: time.Minute,
+		MaxInterval: time.Hour,
+		Jitter:      0.2,
+	})
+
+	stats := model.CategoryCrawlStats{TrackingsCount: 3}
+	lower, upper := 16*time.Minute, 24*time.Minute
+
+	intervals := make(map[time.Duration]struct{})
+	for i := 0; i < 1000; i++ {
+		interval := scheduler.NextInterval(stats)
+		if interval < lower || interval > upper {
+			t.Fatalf("expected interval within [%s, %s], got %s", lower, upper, interval)
+		}
+		intervals[interval] = struct{}{}
+
+		if resume := scheduler.ResumeInterval(); resume < 48*time.Second || resume > 72*time.Second {
+			t.Fatalf("expected resume interval within 20%% of a minute, got %s", resume)
+		}
+	}
+
+	if len(intervals) < 2 {
+		t.Errorf("expected jittered intervals to differ, got %v", intervals)
+	}
+}
@@ -10,34 +10,62 @@ import (
 type TrackingRepository interface {
 	FindMatchTracking(ctx context.Context, categoryID uint64) ([]model.TrackingResult, error)
 	SaveTrackingLog(ctx context.Context, log model.TrackingLog) error
+	SavePriceBaselines(ctx context.Context, categoryID uint64) error
+	FindNewArrivals(ctx context.Context, categoryID uint64, productIDs []uint64) ([]model.NewArrivalResult, error)
+	FindLowStock(ctx context.Context, categoryID uint64) ([]model.LowStockResult, error)
+	SaveLowStockLog(ctx context.Context, result model.LowStockResult) error
+	ResetLowStockLogs(ctx context.Context, categoryID uint64) error
+}
+
+type StockRepository interface {
+	FindBackInStock(ctx context.Context, categoryID uint64) ([]model.StockResult, error)
+	DeleteSubscription(ctx context.Context, subscription model.StockSubscription) error
 }
 
 type NotificationSender interface {
 	Send(ctx context.Context, message model.TrackingResult) error
+	SendBackInStock(ctx context.Context, message model.StockResult) error
+	SendNewArrivals(ctx context.Context, message model.NewArrivalsMessage) error
+	SendLowStock(ctx context.Context, message model.LowStockResult) error
 }
 
 type TrackingService struct {
 	logger             log.Logger
 	trackingRepository TrackingRepository
+	stockRepository    StockRepository
 	notificationSender NotificationSender
 }
 
-func NewTrackingService(logger log.Logger, trackingRepository TrackingRepository, notificationSender NotificationSender) *TrackingService {
+func NewTrackingService(
+	logger log.Logger,
+	trackingRepository TrackingRepository,
+	stockRepository StockRepository,
+	notificationSender NotificationSender,
+) *TrackingService {
 	return &TrackingService{
 		logger:             logger,
 		trackingRepository: trackingRepository,
+		stockRepository:    stockRepository,
 		notificationSender: notificationSender,
 	}
 }
 
 func (s *TrackingService) SendNotifications(ctx context.Context, categoryID uint64) error {
-	trackingResults, err := s.trackingRepository.FindMatchTracking(ctx, categoryID)
-	if err != nil {
+	if err := s.sendPriceNotifications(ctx, categoryID); err != nil {
 		return err
 	}
 
-	if len(trackingResults) == 0 {
-		return nil
+	if err := s.sendBackInStockNotifications(ctx, categoryID); err != nil {
+		return err
+	}
+
+	return s.sendLowStockNotifications(ctx, categoryID)
+}
+
+func (s *TrackingService) sendPriceNotifications(ctx context.Context, categoryID uint64) error {
+	trackingResults, err := s.trackingRepository.FindMatchTracking(ctx, categoryID)
+	if err != nil {
+		return err
 	}
 
 	for _, tracking := range trackingResults {
@@ -52,7 +80,7 @@ func (s *TrackingService) SendNotifications(ctx context.Context, categoryID uint
 			ChatID:    tracking.ChatID,
 			SizeID:    tracking.SizeID,
 			ProductID: tracking.ProductID,
-			Price:     tracking.CurrentPriceInt,
+			Price:     tracking.CurrentPrice,
 		}
 
 		if err = s.trackingRepository.SaveTrackingLog(ctx, trackingLog); err != nil {
@@ -64,5 +92,98 @@ func (s *TrackingService) SendNotifications(ctx context.Context, categoryID uint
 		}
 	}
 
+	return s.trackingRepository.SavePriceBaselines(ctx, categoryID)
+}
+
+func (s *TrackingService) sendBackInStockNotifications(ctx context.Context, categoryID uint64) error {
+	stockResults, err := s.stockRepository.FindBackInStock(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+
+	for _, stock := range stockResults {
+		if err = s.notificationSender.SendBackInStock(ctx, stock); err != nil {
+			s.logger.Error().Err(err).
+				Int64("chat_id", stock.ChatID).
+				Msg("failed send notification about back in stock")
+			continue
+		}
+
+		subscription := model.StockSubscription{
+			ChatID:    stock.ChatID,
+			ProductID: stock.ProductID,
+			SizeID:    stock.SizeID,
+		}
+
+		if err = s.stockRepository.DeleteSubscription(ctx, subscription); err != nil {
+			s.logger.Error().Err(err).
+				Int64("chat_id", stock.ChatID).
+				Uint64("product_id", stock.ProductID).
+				Uint64("size_id", stock.SizeID).
+				Msg("failed delete stock subscription")
+		}
+	}
+
+	return nil
+}
+
+func (s *TrackingService) sendLowStockNotifications(ctx context.Context, categoryID uint64) error {
+	if err := s.trackingRepository.ResetLowStockLogs(ctx, categoryID); err != nil {
+		return err
+	}
+
+	results, err := s.trackingRepository.FindLowStock(ctx, categoryID)
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if err = s.notificationSender.SendLowStock(ctx, result); err != nil {
+			s.logger.Error().Err(err).
+				Int64("chat_id", result.ChatID).
+				Msg("failed send notification about low stock")
+			continue
+		}
+
+		if err = s.trackingRepository.SaveLowStockLog(ctx, result); err != nil {
+			s.logger.Error().Err(err).
+				Int64("chat_id", result.ChatID).
+				Uint64("product_id", result.ProductID).
+				Uint64("size_id", result.SizeID).
+				Msg("failed save low stock log")
+		}
+	}
+
+	return nil
+}
+
+func (s *TrackingService) SendNewArrivals(ctx context.Context, categoryID uint64, productIDs []uint64) error {
+	results, err := s.trackingRepository.FindNewArrivals(ctx, categoryID, productIDs)
+	if err != nil {
+		return err
+	}
+
+	var messages []model.NewArrivalsMessage
+	for _, result := range results {
+		if len(messages) == 0 || messages[len(messages)-1].ChatID != result.ChatID {
+			messages = append(messages, model.NewArrivalsMessage{
+				ChatID:        result.ChatID,
+				CategoryTitle: result.CategoryTitle,
+				CategoryEmoji: result.CategoryEmoji,
+			})
+		}
+
+		message := &messages[len(messages)-1]
+		message.Items = append(message.Items, result)
+	}
+
+	for _, message := range messages {
+		if err = s.notificationSender.SendNewArrivals(ctx, message); err != nil {
+			s.logger.Error().Err(err).
+				Int64("chat_id", message.ChatID).
+				Msg("failed send notification about new arrivals")
+		}
+	}
+
 	return nil
 }
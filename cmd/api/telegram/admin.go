@@ -0,0 +1,394 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/background"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	statsCommand       = "stats"
+	broadcastCommand   = "broadcast"
+	crawlCommand       = "crawl"
+	addCategoryCommand = "addcategory"
+	addRegionCommand   = "addregion"
+	treeCommand        = "tree"
+	enableCommand      = "enablecategory"
+	syncTreeCommand    = "synccategories"
+
+	maxRegionNameLength = 100
+	maxMessageLength    = 4000
+)
+
+type StatsRepository interface {
+	GetStats(ctx context.Context) (model.Stats, error)
+}
+
+type ChatListRepository interface {
+	GetChatIDs(ctx context.Context) ([]int64, error)
+}
+
+type AdminCategoryRepository interface {
+	GetCategoryByName(ctx context.Context, name string) (model.Category, error)
+}
+
+type ProductUpdater interface {
+	UpdateProducts(ctx context.Context, category model.Category) error
+}
+
+type CategoryAdder interface {
+	AddCategory(ctx context.Context, pageURL string, title string, emoji string) (model.Category, error)
+	EnableCategory(ctx context.Context, nodeID uint64, title string, emoji string) (model.Category, error)
+	SyncCategoryTree(ctx context.Context) error
+}
+
+type AdminCategoryTreeRepository interface {
+	GetChildren(ctx context.Context, parentID uint64) ([]model.CategoryNode, error)
+}
+
+type AdminRegionRepository interface {
+	AddRegion(ctx context.Context, region model.Region) (uint64, error)
+}
+
+type adminHandler struct {
+	logger log.Logger
+	admins []int64
+
+	worker *background.Worker
+	sender *Sender
+
+	statsRepository    StatsRepository
+	chatRepository     ChatListRepository
+	categoryRepository AdminCategoryRepository
+	productUpdater     ProductUpdater
+	categoryAdder      CategoryAdder
+	regionRepository   AdminRegionRepository
+	treeRepository     AdminCategoryTreeRepository
+}
+
+func newAdminHandler(
+	logger log.Logger,
+	admins []int64,
+	worker *background.Worker,
+	sender *Sender,
+	statsRepository StatsRepository,
+	chatRepository ChatListRepository,
+	categoryRepository AdminCategoryRepository,
+	productUpdater ProductUpdater,
+	categoryAdder CategoryAdder,
+	regionRepository AdminRegionRepository,
+	treeRepository AdminCategoryTreeRepository,
+) *adminHandler {
+	return &adminHandler{
+		logger:             logger,
+		admins:             admins,
+		worker:             worker,
+		sender:             sender,
+		statsRepository:    statsRepository,
+		chatRepository:     chatRepository,
+		categoryRepository: categoryRepository,
+		productUpdater:     productUpdater,
+		categoryAdder:      categoryAdder,
+		regionRepository:   regionRepository,
+		treeRepository:     treeRepository,
+	}
+}
+
+func (h *adminHandler) OnlyAdmins(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		if update.Message == nil || update.Message.From == nil || !slices.Contains(h.admins, update.Message.From.ID) {
+			return
+		}
+
+		next(ctx, b, update)
+	}
+}
+
+func (h *adminHandler) ShowStats(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowStats")
+
+	chatID := update.Message.Chat.ID
+	stats, err := h.statsRepository.GetStats(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "ShowStats").Msg("get stats failed")
+		sendMessage(ctx, h.logger, b, chatID, "Не удалось получить статистику", "ShowStats")
+		return
+	}
+
+	const messageText = `<b>Пользователи:</b> %d
+<b>Отслеживания:</b> %d
+<b>Товары:</b> %d
+
+<b>Категории:</b>`
+
+	const categoryText = "%s %s: %d товаров, обновлено: %s"
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(messageText, stats.ChatsCount, stats.TrackingsCount, stats.ProductsCount))
+
+	for _, category := range stats.Categories {
+		lastCrawl := category.LastCrawl
+		if lastCrawl == "" {
+			lastCrawl = "никогда"
+		}
+
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(categoryText, category.CategoryEmoji, category.CategoryTitle, category.ProductsCount, lastCrawl))
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      sb.String(),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "ShowStats").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *adminHandler) Broadcast(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "Broadcast")
+
+	chatID := update.Message.Chat.ID
+	text := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+broadcastCommand))
+	if text == "" {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /broadcast <текст сообщения>", "Broadcast")
+		return
+	}
+
+	chatIDs, err := h.chatRepository.GetChatIDs(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "Broadcast").Msg("get chats failed")
+		sendMessage(ctx, h.logger, b, chatID, "Не удалось получить список чатов", "Broadcast")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Рассылка запущена, получателей: %d", len(chatIDs)), "Broadcast")
+
+	h.worker.Run(ctx, "broadcast", func(ctx context.Context) error {
+		failed := 0
+		for _, id := range chatIDs {
+			if err := h.sender.SendText(ctx, id, text); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+
+				h.logger.Error().Err(err).Int64("chat_id", id).Msg("failed send broadcast message")
+				failed++
+			}
+		}
+
+		sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Рассылка завершена, отправлено: %d, ошибок: %d", len(chatIDs)-failed, failed), "Broadcast")
+		return nil
+	})
+}
+
+func (h *adminHandler) Crawl(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "Crawl")
+
+	chatID := update.Message.Chat.ID
+	name := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+crawlCommand))
+	if name == "" {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /crawl <название категории>", "Crawl")
+		return
+	}
+
+	category, err := h.categoryRepository.GetCategoryByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, model.ErrCategoryNotFound) {
+			sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Категория %s не найдена", name), "Crawl")
+			return
+		}
+
+		h.logger.Error().Err(err).Str("handler", "Crawl").Str("category", name).Msg("get category failed")
+		sendMessage(ctx, h.logger, b, chatID, "Не удалось получить категорию", "Crawl")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Обновление категории %s %s запущено", category.Emoji, category.Title), "Crawl")
+
+	h.worker.Run(ctx, "crawl "+category.Name, func(ctx context.Context) error {
+		if err := h.productUpdater.UpdateProducts(ctx, category); err != nil {
+			if errors.Is(err, model.ErrCrawlInProgress) {
+				sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Категория %s %s уже обновляется, дождитесь окончания", category.Emoji, category.Title), "Crawl")
+				return nil
+			}
+
+			sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Обновление категории %s %s завершилось ошибкой: %s", category.Emoji, category.Title, err), "Crawl")
+			return err
+		}
+
+		sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Обновление категории %s %s завершено", category.Emoji, category.Title), "Crawl")
+		return nil
+	})
+}
+
+func (h *adminHandler) AddCategory(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddCategory")
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(strings.TrimPrefix(update.Message.Text, "/"+addCategoryCommand))
+	if len(args) < 2 {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /addcategory <ссылка на каталог WB или Ozon> <эмодзи> [название]", "AddCategory")
+		return
+	}
+
+	category, err := h.categoryAdder.AddCategory(ctx, args[0], strings.Join(args[2:], " "), args[1])
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "AddCategory").Str("url", args[0]).Msg("add category failed")
+
+		text := fmt.Sprintf("Не удалось добавить категорию: %s", err)
+		if errors.Is(err, model.ErrCategoryNotFound) {
+			text = "Категория по ссылке не найдена в каталоге WB"
+		} else if errors.Is(err, model.ErrUnknownMarketplace) {
+			text = "Ссылка не ведет на поддерживаемый магазин, доступны WB и Ozon"
+		} else if errors.Is(err, model.ErrCategoryExists) {
+			text = "Такая категория уже добавлена"
+		}
+
+		sendMessage(ctx, h.logger, b, chatID, text, "AddCategory")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Категория %s %s (%s) добавлена", category.Emoji, category.Title, category.Name), "AddCategory")
+}
+
+func (h *adminHandler) AddRegion(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddRegion")
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(strings.TrimPrefix(update.Message.Text, "/"+addRegionCommand))
+	if len(args) < 2 {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /addregion <dest города или пункта выдачи> <название>", "AddRegion")
+		return
+	}
+
+	dest, err := strconv.ParseInt(args[0], 10, 64)
+	name := strings.Join(args[1:], " ")
+	if err != nil || dest == 0 || utf8.RuneCountInString(name) > maxRegionNameLength {
+		sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Некорректный регион: dest должен быть ненулевым числом, название не длиннее %d символов", maxRegionNameLength), "AddRegion")
+		return
+	}
+
+	id, err := h.regionRepository.AddRegion(ctx, model.Region{Name: name, Dest: dest})
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "AddRegion").Int64("dest", dest).Msg("add region failed")
+		sendMessage(ctx, h.logger, b, chatID, "Не удалось добавить регион", "AddRegion")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Регион %s (dest %d) добавлен, id: %d", name, dest, id), "AddRegion")
+}
+
+func (h *adminHandler) ShowTree(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowTree")
+
+	chatID := update.Message.Chat.ID
+	var parentID uint64
+	if arg := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+treeCommand)); arg != "" {
+		var err error
+		if parentID, err = strconv.ParseUint(arg, 10, 64); err != nil {
+			sendMessage(ctx, h.logger, b, chatID, "Использование: /tree [id раздела]", "ShowTree")
+			return
+		}
+	}
+
+	nodes, err := h.treeRepository.GetChildren(ctx, parentID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "ShowTree").Uint64("parent_id", parentID).Msg("get category tree failed")
+		sendMessage(ctx, h.logger, b, chatID, "Не удалось получить дерево категорий", "ShowTree")
+		return
+	}
+
+	if len(nodes) == 0 {
+		sendMessage(ctx, h.logger, b, chatID, "Раздел пуст, дерево категорий обновляется командой /synccategories", "ShowTree")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("✅ включена, 📂 есть подразделы, /enablecategory <id> <эмодзи> [название] включает категорию\n")
+
+	for _, node := range nodes {
+		line := fmt.Sprintf("\n%d %s", node.ID, node.Name)
+		if node.HasChildren {
+			line += " 📂"
+		}
+		if node.Category.ID > 0 {
+			line += fmt.Sprintf(" ✅ %s %s", node.Category.Emoji, node.Category.Title)
+		} else if node.Shard == "" {
+			line += " (без каталога)"
+		}
+
+		if sb.Len()+len(line) > maxMessageLength {
+			sb.WriteString("\n…")
+			break
+		}
+		sb.WriteString(line)
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, sb.String(), "ShowTree")
+}
+
+func (h *adminHandler) EnableCategory(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "EnableCategory")
+
+	chatID := update.Message.Chat.ID
+	args := strings.Fields(strings.TrimPrefix(update.Message.Text, "/"+enableCommand))
+	if len(args) < 2 {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /enablecategory <id раздела из /tree> <эмодзи> [название]", "EnableCategory")
+		return
+	}
+
+	nodeID, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		sendMessage(ctx, h.logger, b, chatID, "id раздела должен быть числом, его можно найти командой /tree", "EnableCategory")
+		return
+	}
+
+	category, err := h.categoryAdder.EnableCategory(ctx, nodeID, strings.Join(args[2:], " "), args[1])
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "EnableCategory").Uint64("node_id", nodeID).Msg("enable category failed")
+
+		text := fmt.Sprintf("Не удалось включить категорию: %s", err)
+		if errors.Is(err, model.ErrCategoryNodeNotFound) {
+			text = "Раздел не найден в дереве категорий"
+		} else if errors.Is(err, model.ErrCategoryExists) {
+			text = "Такая категория уже добавлена"
+		}
+
+		sendMessage(ctx, h.logger, b, chatID, text, "EnableCategory")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Категория %s %s (%s) включена", category.Emoji, category.Title, category.Name), "EnableCategory")
+}
+
+func (h *adminHandler) SyncTree(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "SyncTree")
+
+	chatID := update.Message.Chat.ID
+	sendMessage(ctx, h.logger, b, chatID, "Обновление дерева категорий запущено", "SyncTree")
+
+	h.worker.Run(ctx, "sync categories", func(ctx context.Context) error {
+		if err := h.categoryAdder.SyncCategoryTree(ctx); err != nil {
+			sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Обновление дерева категорий завершилось ошибкой: %s", err), "SyncTree")
+			return err
+		}
+
+		sendMessage(ctx, h.logger, b, chatID, "Дерево категорий обновлено", "SyncTree")
+		return nil
+	})
+}
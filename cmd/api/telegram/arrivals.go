@@ -0,0 +1,230 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	arrivalsCategoriesURL = "/arrivalscategories/"
+	arrivalsPricesURL     = "/arrivalsprices/"
+	addArrivalsURL        = "/addarrivals/"
+)
+
+var arrivalsMaxPrices = [][]int{
+	{0},
+	{1000, 2000, 3000},
+	{5000, 7000, 10000},
+}
+
+type arrivalsHandler struct {
+	logger log.Logger
+
+	categoryRepository CategoryRepository
+	sizeRepository     SizeRepository
+	trackingRepository TrackingRepository
+}
+
+func newArrivalsHandler(
+	logger log.Logger,
+	categoryRepository CategoryRepository,
+	sizeRepository SizeRepository,
+	trackingRepository TrackingRepository,
+) *arrivalsHandler {
+	return &arrivalsHandler{
+		logger:             logger,
+		categoryRepository: categoryRepository,
+		sizeRepository:     sizeRepository,
+		trackingRepository: trackingRepository,
+	}
+}
+
+func (h *arrivalsHandler) ShowCategoryOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowArrivalsCategoryOptions")
+	showCategoryMenu(ctx, b, h.logger, h.categoryRepository, update.Message.Chat.ID, arrivalsMenuFlow, 0, "ShowArrivalsCategoryOptions")
+}
+
+func (h *arrivalsHandler) ShowSizeOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowArrivalsSizeOptions")
+	h.showSizeOptions(ctx, b, update, arrivalsCategoriesURL, arrivalsPricesURL, "ShowArrivalsSizeOptions")
+}
+
+func (h *arrivalsHandler) ShowMaxPriceOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowArrivalsMaxPriceOptions")
+	h.showMaxPriceOptions(ctx, b, update, arrivalsPricesURL, addArrivalsURL, "Выберите максимальную цену новинок:", "ShowArrivalsMaxPriceOptions")
+}
+
+func (h *arrivalsHandler) AddTracking(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddArrivalsTracking")
+
+	values, ok := h.callbackValues(update, addArrivalsURL, 3, "AddArrivalsTracking")
+	if !ok {
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	trackingSettings := model.TrackingSettings{
+		ChatID:     chatID,
+		SizeID:     values[1],
+		CategoryID: values[0],
+		Type:       model.TrackingTypeNewArrivals,
+		MaxPrice:   model.Rubles(values[2]),
+	}
+
+	if err := h.trackingRepository.AddTracking(ctx, trackingSettings); err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed add new arrivals tracking settings")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось добавить настройку отслеживания, попробуйте позже, мы уже чиним поломку :С", "AddArrivalsTracking")
+		return
+	}
+
+	const messageText = `Вы добавили отслеживание новинок для следующих параметров:
+<b>Категория</b>: <i>%s</i> %s
+<b>Размер</b>: <i>%s</i> 📏
+<b>Цена</b>: <i>%s</i> 🆕`
+
+	sizeData, err := h.sizeRepository.GetSizeCategoryInfo(ctx, trackingSettings.SizeID, trackingSettings.CategoryID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed get size category info")
+		sizeData.Name = "не удалось получить данные :С"
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      fmt.Sprintf(messageText, sizeData.CategoryTitle, sizeData.CategoryEmoji, sizeData.Name, formatMaxPrice(trackingSettings.MaxPrice)),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "AddArrivalsTracking").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *arrivalsHandler) showSizeOptions(ctx context.Context, b *bot.Bot, update *models.Update, prefix string, nextURL string, handlerName string) {
+	values, ok := h.callbackValues(update, prefix, 1, handlerName)
+	if !ok {
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	categoryID := values[0]
+
+	sizes, err := h.sizeRepository.GetSizesInfo(ctx, categoryID)
+	if err != nil || len(sizes) == 0 {
+		h.logger.Error().Err(err).Str("handler", handlerName).Msg("get sizes failed")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению для данной категории пока нет информации о товарах, попробуйте позже :)", handlerName)
+		return
+	}
+
+	for start := 0; start < len(sizes); start += buttonsPerMessage {
+		end := min(start+buttonsPerMessage, len(sizes))
+
+		var rows [][]models.InlineKeyboardButton
+		for i := start; i < end; i += buttonsPerRow {
+			var row []models.InlineKeyboardButton
+			for _, size := range sizes[i:min(i+buttonsPerRow, end)] {
+				row = append(row, models.InlineKeyboardButton{
+					Text:         size.Name,
+					CallbackData: fmt.Sprintf("%s%d:%d", nextURL, categoryID, size.ID),
+				})
+			}
+			rows = append(rows, row)
+		}
+
+		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "Выберите размер:",
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: rows,
+			},
+		})
+		if err != nil {
+			h.logger.Error().Err(err).
+				Str("handler", handlerName).
+				Int64("chat_id", chatID).
+				Msg("failed send message")
+		}
+	}
+}
+
+func (h *arrivalsHandler) showMaxPriceOptions(
+	ctx context.Context,
+	b *bot.Bot,
+	update *models.Update,
+	prefix string,
+	nextURL string,
+	text string,
+	handlerName string,
+) {
+	values, ok := h.callbackValues(update, prefix, 2, handlerName)
+	if !ok {
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	categoryID, sizeID := values[0], values[1]
+
+	var rows [][]models.InlineKeyboardButton
+	for _, prices := range arrivalsMaxPrices {
+		var row []models.InlineKeyboardButton
+		for _, price := range prices {
+			row = append(row, models.InlineKeyboardButton{
+				Text:         formatMaxPrice(model.Rubles(uint64(price))),
+				CallbackData: fmt.Sprintf("%s%d:%d:%d", nextURL, categoryID, sizeID, price),
+			})
+		}
+		rows = append(rows, row)
+	}
+
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		},
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", handlerName).
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *arrivalsHandler) callbackValues(update *models.Update, prefix string, count int, handlerName string) ([]uint64, bool) {
+	if update.CallbackQuery == nil {
+		h.logger.Error().Str("handler", handlerName).Msg("callback query is empty")
+		return nil, false
+	}
+
+	data, isFound := strings.CutPrefix(update.CallbackQuery.Data, prefix)
+	parts := strings.Split(data, ":")
+	if !isFound || len(parts) != count {
+		h.logger.Error().Str("handler", handlerName).
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't extract data from callback query data")
+		return nil, false
+	}
+
+	values := make([]uint64, 0, count)
+	for _, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			h.logger.Error().Err(err).Str("handler", handlerName).
+				Str("callback_data", update.CallbackQuery.Data).
+				Msg("can't parse callback query data")
+			return nil, false
+		}
+		values = append(values, value)
+	}
+
+	return values, true
+}
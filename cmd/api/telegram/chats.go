@@ -0,0 +1,28 @@
+package telegram
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type ChatRepository interface {
+	SaveChat(ctx context.Context, chatID int64) error
+	DeleteChat(ctx context.Context, chatID int64) error
+}
+
+func NewChatMiddlewareOption(logger log.Logger, repository ChatRepository) bot.Option {
+	return bot.WithMiddlewares(func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			if update.Message != nil {
+				if err := repository.SaveChat(ctx, update.Message.Chat.ID); err != nil {
+					logger.Error().Err(err).Int64("chat_id", update.Message.Chat.ID).Msg("failed save chat")
+				}
+			}
+
+			next(ctx, b, update)
+		}
+	})
+}
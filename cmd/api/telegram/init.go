@@ -1,9 +1,11 @@
 package telegram
 
 import (
+	"context"
 	"runtime/debug"
 
 	"github.com/go-telegram/bot"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/background"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/telegram"
 )
@@ -14,18 +16,79 @@ func InitHandlers(
 	categoryRepository CategoryRepository,
 	sizeRepository SizeRepository,
 	trackingRepository TrackingRepository,
+	stockRepository StockRepository,
+	regionRepository RegionRepository,
+	worker *background.Worker,
+	sourceAdder SourceAdder,
+	productUpdater ProductUpdater,
 ) {
 	tracking := newTrackingHandler(logger, categoryRepository, sizeRepository, trackingRepository)
+	stock := newStockHandler(logger, stockRepository)
+	arrivals := newArrivalsHandler(logger, categoryRepository, sizeRepository, trackingRepository)
+	lowStock := newLowStockHandler(logger, categoryRepository, sizeRepository, trackingRepository)
+	region := newRegionHandler(logger, regionRepository)
+	source := newSourceHandler(logger, worker, sourceAdder, productUpdater)
+	menu := newCategoryMenuHandler(logger, categoryRepository)
+
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, categoryMenuURL, bot.MatchTypePrefix, menu.ShowCategoryMenu)
 
 	client.RegisterHandler(bot.HandlerTypeMessageText, "/addtracking", bot.MatchTypeExact, tracking.ShowCategoryTrackingOptions)
 	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, trackingCategoriesURL, bot.MatchTypePrefix, tracking.ShowSizeTrackingOptions)
 	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, showDiffPricesURL, bot.MatchTypePrefix, tracking.ShowDiffPriceOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, showPriceTypesURL, bot.MatchTypePrefix, tracking.ShowPriceTypeOptions)
 	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, addTrackingURL, bot.MatchTypePrefix, tracking.AddTracking)
 
+	client.RegisterHandler(bot.HandlerTypeMessageText, addSearchCommand, bot.MatchTypeCommandStartOnly, source.AddSearch)
+	client.RegisterHandler(bot.HandlerTypeMessageText, brandCommand, bot.MatchTypeCommandStartOnly, source.AddBrand)
+	client.RegisterHandler(bot.HandlerTypeMessageText, sellerCommand, bot.MatchTypeCommandStartOnly, source.AddSeller)
+
 	client.RegisterHandler(bot.HandlerTypeMessageText, "/showtracking", bot.MatchTypeExact, tracking.ShowTrackingSettings)
 
 	client.RegisterHandler(bot.HandlerTypeMessageText, "/deletetracking", bot.MatchTypeExact, tracking.ShowDeleteTrackingSettings)
 	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, deleteTrackingURL, bot.MatchTypePrefix, tracking.DeleteTrackingSettings)
+
+	client.RegisterHandler(bot.HandlerTypeMessageText, "/addnewarrivals", bot.MatchTypeExact, arrivals.ShowCategoryOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, arrivalsCategoriesURL, bot.MatchTypePrefix, arrivals.ShowSizeOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, arrivalsPricesURL, bot.MatchTypePrefix, arrivals.ShowMaxPriceOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, addArrivalsURL, bot.MatchTypePrefix, arrivals.AddTracking)
+
+	client.RegisterHandler(bot.HandlerTypeMessageText, "/addlowstock", bot.MatchTypeExact, lowStock.ShowCategoryOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, lowStockCategoriesURL, bot.MatchTypePrefix, lowStock.ShowSizeOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, lowStockPricesURL, bot.MatchTypePrefix, lowStock.ShowMaxPriceOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, lowStockThresholdsURL, bot.MatchTypePrefix, lowStock.ShowThresholdOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, addLowStockURL, bot.MatchTypePrefix, lowStock.AddTracking)
+
+	client.RegisterHandler(bot.HandlerTypeMessageText, "/region", bot.MatchTypeExact, region.ShowRegionOptions)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, setRegionURL, bot.MatchTypePrefix, region.SetRegion)
+
+	client.RegisterHandler(bot.HandlerTypeMessageText, notifyBackCommand, bot.MatchTypeCommandStartOnly, stock.ShowUnavailableSizes)
+	client.RegisterHandler(bot.HandlerTypeCallbackQueryData, stockSubscribeURL, bot.MatchTypePrefix, stock.AddSubscription)
+}
+
+func InitAdminHandlers(
+	logger log.Logger,
+	client *telegram.BotClient,
+	admins []int64,
+	worker *background.Worker,
+	sender *Sender,
+	statsRepository StatsRepository,
+	chatRepository ChatListRepository,
+	categoryRepository AdminCategoryRepository,
+	productUpdater ProductUpdater,
+	categoryAdder CategoryAdder,
+	regionRepository AdminRegionRepository,
+	treeRepository AdminCategoryTreeRepository,
+) {
+	admin := newAdminHandler(logger, admins, worker, sender, statsRepository, chatRepository, categoryRepository, productUpdater, categoryAdder, regionRepository, treeRepository)
+
+	client.RegisterHandler(bot.HandlerTypeMessageText, statsCommand, bot.MatchTypeCommandStartOnly, admin.ShowStats, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, broadcastCommand, bot.MatchTypeCommandStartOnly, admin.Broadcast, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, crawlCommand, bot.MatchTypeCommandStartOnly, admin.Crawl, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, addCategoryCommand, bot.MatchTypeCommandStartOnly, admin.AddCategory, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, addRegionCommand, bot.MatchTypeCommandStartOnly, admin.AddRegion, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, treeCommand, bot.MatchTypeCommandStartOnly, admin.ShowTree, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, enableCommand, bot.MatchTypeCommandStartOnly, admin.EnableCategory, admin.OnlyAdmins)
+	client.RegisterHandler(bot.HandlerTypeMessageText, syncTreeCommand, bot.MatchTypeCommandStartOnly, admin.SyncTree, admin.OnlyAdmins)
 }
 
 func recovery(logger log.Logger, handlerName string) {
@@ -44,3 +107,16 @@ func recovery(logger log.Logger, handlerName string) {
 		event.Msg("recovered from panic")
 	}
 }
+
+func sendMessage(ctx context.Context, logger log.Logger, b *bot.Bot, chatID int64, text string, handlerName string) {
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+	if err != nil {
+		logger.Error().Err(err).
+			Str("handler", handlerName).
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
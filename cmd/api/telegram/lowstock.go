@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	lowStockCategoriesURL = "/lowstockcategories/"
+	lowStockPricesURL     = "/lowstockprices/"
+	lowStockThresholdsURL = "/lowstockthresholds/"
+	addLowStockURL        = "/addlowstock/"
+)
+
+var lowStockThresholds = []int{3, 5, 10}
+
+type lowStockHandler struct {
+	*arrivalsHandler
+}
+
+func newLowStockHandler(
+	logger log.Logger,
+	categoryRepository CategoryRepository,
+	sizeRepository SizeRepository,
+	trackingRepository TrackingRepository,
+) *lowStockHandler {
+	return &lowStockHandler{
+		arrivalsHandler: newArrivalsHandler(logger, categoryRepository, sizeRepository, trackingRepository),
+	}
+}
+
+func (h *lowStockHandler) ShowCategoryOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowLowStockCategoryOptions")
+	showCategoryMenu(ctx, b, h.logger, h.categoryRepository, update.Message.Chat.ID, lowStockMenuFlow, 0, "ShowLowStockCategoryOptions")
+}
+
+func (h *lowStockHandler) ShowSizeOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowLowStockSizeOptions")
+	h.showSizeOptions(ctx, b, update, lowStockCategoriesURL, lowStockPricesURL, "ShowLowStockSizeOptions")
+}
+
+func (h *lowStockHandler) ShowMaxPriceOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowLowStockMaxPriceOptions")
+	h.showMaxPriceOptions(ctx, b, update, lowStockPricesURL, lowStockThresholdsURL, "Выберите максимальную цену товара:", "ShowLowStockMaxPriceOptions")
+}
+
+func (h *lowStockHandler) ShowThresholdOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowLowStockThresholdOptions")
+
+	values, ok := h.callbackValues(update, lowStockThresholdsURL, 3, "ShowLowStockThresholdOptions")
+	if !ok {
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+
+	var row []models.InlineKeyboardButton
+	for _, threshold := range lowStockThresholds {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("меньше %d шт.", threshold),
+			CallbackData: fmt.Sprintf("%s%d:%d:%d:%d", addLowStockURL, values[0], values[1], values[2], threshold),
+		})
+	}
+
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   "Когда сообщить об остатке?",
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{row},
+		},
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "ShowLowStockThresholdOptions").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *lowStockHandler) AddTracking(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddLowStockTracking")
+
+	values, ok := h.callbackValues(update, addLowStockURL, 4, "AddLowStockTracking")
+	if !ok {
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	trackingSettings := model.TrackingSettings{
+		ChatID:         chatID,
+		SizeID:         values[1],
+		CategoryID:     values[0],
+		Type:           model.TrackingTypeLowStock,
+		MaxPrice:       model.Rubles(values[2]),
+		StockThreshold: uint32(values[3]),
+	}
+
+	if err := h.trackingRepository.AddTracking(ctx, trackingSettings); err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed add low stock tracking settings")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось добавить настройку отслеживания, попробуйте позже, мы уже чиним поломку :С", "AddLowStockTracking")
+		return
+	}
+
+	const messageText = `Вы добавили отслеживание последних штук для следующих параметров:
+<b>Категория</b>: <i>%s</i> %s
+<b>Размер</b>: <i>%s</i> 📏
+<b>Цена</b>: <i>%s</i>
+<b>Остаток</b>: <i>меньше %d шт.</i> ⏳`
+
+	sizeData, err := h.sizeRepository.GetSizeCategoryInfo(ctx, trackingSettings.SizeID, trackingSettings.CategoryID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed get size category info")
+		sizeData.Name = "не удалось получить данные :С"
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text: fmt.Sprintf(
+			messageText,
+			sizeData.CategoryTitle,
+			sizeData.CategoryEmoji,
+			sizeData.Name,
+			formatMaxPrice(trackingSettings.MaxPrice),
+			trackingSettings.StockThreshold,
+		),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "AddLowStockTracking").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
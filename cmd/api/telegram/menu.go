@@ -0,0 +1,150 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	categoryMenuURL = "/categorymenu/"
+
+	trackingMenuFlow = "t"
+	arrivalsMenuFlow = "a"
+	lowStockMenuFlow = "l"
+)
+
+// categoryMenuFlow is a picker started by a command, the chosen category continues to the next step of the command.
+type categoryMenuFlow struct {
+	text         string
+	callbackData func(category model.Category) string
+}
+
+var categoryMenuFlows = map[string]categoryMenuFlow{
+	trackingMenuFlow: {
+		text: "Выберите категорию товара для отслеживания:",
+		callbackData: func(category model.Category) string {
+			return fmt.Sprintf("%s%d:%s:%s", trackingCategoriesURL, category.ID, category.Title, category.Emoji)
+		},
+	},
+	arrivalsMenuFlow: {
+		text: "Выберите категорию для отслеживания новинок:",
+		callbackData: func(category model.Category) string {
+			return fmt.Sprintf("%s%d", arrivalsCategoriesURL, category.ID)
+		},
+	},
+	lowStockMenuFlow: {
+		text: "Выберите категорию для отслеживания последних штук в размере:",
+		callbackData: func(category model.Category) string {
+			return fmt.Sprintf("%s%d", lowStockCategoriesURL, category.ID)
+		},
+	},
+}
+
+type categoryMenuHandler struct {
+	logger             log.Logger
+	categoryRepository CategoryRepository
+}
+
+func newCategoryMenuHandler(logger log.Logger, categoryRepository CategoryRepository) *categoryMenuHandler {
+	return &categoryMenuHandler{
+		logger:             logger,
+		categoryRepository: categoryRepository,
+	}
+}
+
+func (h *categoryMenuHandler) ShowCategoryMenu(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowCategoryMenu")
+
+	if update.CallbackQuery == nil {
+		h.logger.Error().Str("handler", "ShowCategoryMenu").Msg("callback query is empty")
+		return
+	}
+
+	data, isFound := strings.CutPrefix(update.CallbackQuery.Data, categoryMenuURL)
+	flowName, nodeIDStr, isSplit := strings.Cut(data, ":")
+	nodeID, err := strconv.ParseUint(nodeIDStr, 10, 64)
+	if !isFound || !isSplit || err != nil {
+		h.logger.Error().Str("handler", "ShowCategoryMenu").
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't parse category menu from callback query data")
+		return
+	}
+
+	showCategoryMenu(ctx, b, h.logger, h.categoryRepository, update.CallbackQuery.Message.Message.Chat.ID, flowName, nodeID, "ShowCategoryMenu")
+}
+
+// showCategoryMenu sends the children of the node, nodes with enabled descendants open the next level
+// and enabled leaves continue the flow.
+func showCategoryMenu(
+	ctx context.Context,
+	b *bot.Bot,
+	logger log.Logger,
+	categoryRepository CategoryRepository,
+	chatID int64,
+	flowName string,
+	parentID uint64,
+	handlerName string,
+) {
+	flow, ok := categoryMenuFlows[flowName]
+	if !ok {
+		logger.Error().Str("handler", handlerName).Str("flow", flowName).Msg("unknown category menu flow")
+		return
+	}
+
+	nodes, err := categoryRepository.GetCategoryMenu(ctx, parentID)
+	if err != nil || len(nodes) == 0 {
+		logger.Error().Err(err).Str("handler", handlerName).Uint64("parent_id", parentID).Msg("get category menu failed")
+		sendCategoryMenuMessage(ctx, b, logger, chatID, "К сожалению пока данный функционал недоступен, попробуйте позже :С", nil, handlerName)
+		return
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for _, node := range nodes {
+		if node.HasChildren {
+			rows = append(rows, []models.InlineKeyboardButton{{
+				Text:         fmt.Sprintf("📂 %s", node.Name),
+				CallbackData: fmt.Sprintf("%s%s:%d", categoryMenuURL, flowName, node.ID),
+			}})
+			continue
+		}
+
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("%s %s", node.Category.Emoji, node.Category.Title),
+			CallbackData: flow.callbackData(node.Category),
+		}})
+	}
+
+	sendCategoryMenuMessage(ctx, b, logger, chatID, flow.text, rows, handlerName)
+}
+
+func sendCategoryMenuMessage(
+	ctx context.Context,
+	b *bot.Bot,
+	logger log.Logger,
+	chatID int64,
+	text string,
+	rows [][]models.InlineKeyboardButton,
+	handlerName string,
+) {
+	params := &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	}
+	if len(rows) > 0 {
+		params.ReplyMarkup = &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+	}
+
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		logger.Error().Err(err).
+			Str("handler", handlerName).
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
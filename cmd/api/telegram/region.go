@@ -0,0 +1,125 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const setRegionURL = "/setregion/"
+
+type RegionRepository interface {
+	GetRegions(ctx context.Context) ([]model.Region, error)
+	GetRegion(ctx context.Context, id uint64) (model.Region, error)
+	GetChatRegion(ctx context.Context, chatID int64) (model.Region, error)
+	SetChatRegion(ctx context.Context, chatID int64, regionID uint64) error
+}
+
+type regionHandler struct {
+	logger           log.Logger
+	regionRepository RegionRepository
+}
+
+func newRegionHandler(logger log.Logger, regionRepository RegionRepository) *regionHandler {
+	return &regionHandler{
+		logger:           logger,
+		regionRepository: regionRepository,
+	}
+}
+
+func (h *regionHandler) ShowRegionOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowRegionOptions")
+
+	chatID := update.Message.Chat.ID
+	regions, err := h.regionRepository.GetRegions(ctx)
+	if err != nil || len(regions) == 0 {
+		h.logger.Error().Err(err).Str("handler", "ShowRegionOptions").Msg("get regions failed")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению пока данный функционал недоступен, попробуйте позже :С", "ShowRegionOptions")
+		return
+	}
+
+	current, err := h.regionRepository.GetChatRegion(ctx, chatID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "ShowRegionOptions").Int64("chat_id", chatID).Msg("get chat region failed")
+	}
+
+	var rows [][]models.InlineKeyboardButton
+	for _, region := range regions {
+		text := region.Name
+		if region.ID == current.ID {
+			text = "✅ " + text
+		}
+
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         text,
+			CallbackData: fmt.Sprintf("%s%d", setRegionURL, region.ID),
+		}})
+	}
+
+	const messageText = `Цены и наличие на WB зависят от региона доставки.
+<b>Текущий регион</b>: <i>%s</i> 📍
+
+Выберите город или пункт выдачи:`
+
+	currentName := current.Name
+	if currentName == "" {
+		currentName = "не удалось получить данные :С"
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      fmt.Sprintf(messageText, currentName),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		},
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "ShowRegionOptions").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *regionHandler) SetRegion(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "SetRegion")
+
+	if update.CallbackQuery == nil {
+		h.logger.Error().Str("handler", "SetRegion").Msg("callback query is empty")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	regionID, err := strconv.ParseUint(strings.TrimPrefix(update.CallbackQuery.Data, setRegionURL), 10, 64)
+	if err != nil {
+		h.logger.Error().Err(err).Str("handler", "SetRegion").
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't parse callback query data")
+		return
+	}
+
+	region, err := h.regionRepository.GetRegion(ctx, regionID)
+	if err != nil {
+		if !errors.Is(err, model.ErrRegionNotFound) {
+			h.logger.Error().Err(err).Str("handler", "SetRegion").Uint64("region_id", regionID).Msg("get region failed")
+		}
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению этот регион больше недоступен, выберите другой: /region", "SetRegion")
+		return
+	}
+
+	if err = h.regionRepository.SetChatRegion(ctx, chatID, region.ID); err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed set chat region")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось сменить регион, попробуйте позже, мы уже чиним поломку :С", "SetRegion")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, fmt.Sprintf("Регион доставки изменён на %s 📍\nЦены для него появятся после ближайшего обновления каталога.", region.Name), "SetRegion")
+}
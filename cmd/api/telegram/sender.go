@@ -3,6 +3,8 @@ package telegram
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -10,13 +12,24 @@ import (
 	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/telegram"
 )
 
+type SenderConfig struct {
+	MessagesPerSecond int `config:"messages_per_second"`
+}
+
 type Sender struct {
 	client *telegram.BotClient
+	ticker *time.Ticker
 }
 
-func NewSender(client *telegram.BotClient) *Sender {
+func NewSender(client *telegram.BotClient, config SenderConfig) *Sender {
+	interval := time.Second
+	if config.MessagesPerSecond > 0 {
+		interval = time.Second / time.Duration(config.MessagesPerSecond)
+	}
+
 	return &Sender{
 		client: client,
+		ticker: time.NewTicker(interval),
 	}
 }
 
@@ -27,12 +40,26 @@ func (s *Sender) Send(ctx context.Context, message model.TrackingResult) error {
 
 <b>Размер:</b> %s
 
-<b>Старая цена:</b> %.2f
+<b>Старая цена:</b> %s
 
-<b>Новая цена:</b> %.2f
+<b>Новая цена:</b> %s%s
 
 <b>Снижение цены:</b> %d%%`
-	_, err := s.client.SendMessage(ctx, &bot.SendMessageParams{
+
+	var details strings.Builder
+	if message.PriceType == model.PriceTypeProduct {
+		details.WriteString(" (цена товара без доставки)")
+	}
+
+	if message.BasicPrice > message.CurrentPrice {
+		details.WriteString(fmt.Sprintf("\n<b>Цена до скидки продавца:</b> <s>%s</s>", message.BasicPrice))
+	}
+
+	if message.LogisticsPrice > 0 {
+		details.WriteString(fmt.Sprintf("\n<b>Доставка:</b> %s", message.LogisticsPrice))
+	}
+
+	return s.send(ctx, &bot.SendMessageParams{
 		ChatID: message.ChatID,
 		Text: fmt.Sprintf(
 			messageText,
@@ -41,9 +68,106 @@ func (s *Sender) Send(ctx context.Context, message model.TrackingResult) error {
 			message.Size,
 			message.PreviousPrice,
 			message.CurrentPrice,
+			details.String(),
 			message.DiffPercent,
 		),
 		ParseMode: models.ParseModeHTML,
 	})
+}
+
+func (s *Sender) SendBackInStock(ctx context.Context, message model.StockResult) error {
+	const messageText = `<b>%s</b>
+
+<a href="%s">Ссылка на товар</a>
+
+<b>Размер %s снова в наличии!</b>
+
+<b>Цена:</b> %s`
+	return s.send(ctx, &bot.SendMessageParams{
+		ChatID: message.ChatID,
+		Text: fmt.Sprintf(
+			messageText,
+			message.ProductName,
+			message.ProductURL,
+			message.Size,
+			message.CurrentPrice,
+		),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+func (s *Sender) SendLowStock(ctx context.Context, message model.LowStockResult) error {
+	const messageText = `<b>%s</b>
+
+<a href="%s">Ссылка на товар</a>
+
+<b>Последние штуки в размере %s!</b> Осталось: %d шт.
+
+<b>Цена:</b> %s`
+	return s.send(ctx, &bot.SendMessageParams{
+		ChatID: message.ChatID,
+		Text: fmt.Sprintf(
+			messageText,
+			message.ProductName,
+			message.ProductURL,
+			message.Size,
+			message.Quantity,
+			message.CurrentPrice,
+		),
+		ParseMode: models.ParseModeHTML,
+	})
+}
+
+func (s *Sender) SendNewArrivals(ctx context.Context, message model.NewArrivalsMessage) error {
+	const (
+		headerText = "<b>Новинки в категории %s %s</b>"
+		itemText   = "\n\n<a href=\"%s\">%s</a>\n<b>Размер:</b> %s, <b>цена:</b> %s"
+		moreText   = "\n\nИ ещё товаров: %d"
+
+		maxItems = 20
+	)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(headerText, message.CategoryTitle, message.CategoryEmoji))
+
+	for i, item := range message.Items {
+		if i == maxItems {
+			sb.WriteString(fmt.Sprintf(moreText, len(message.Items)-maxItems))
+			break
+		}
+
+		sb.WriteString(fmt.Sprintf(itemText, item.ProductURL, item.ProductName, item.Size, item.CurrentPrice))
+	}
+
+	return s.send(ctx, &bot.SendMessageParams{
+		ChatID:    message.ChatID,
+		Text:      sb.String(),
+		ParseMode: models.ParseModeHTML,
+		LinkPreviewOptions: &models.LinkPreviewOptions{
+			IsDisabled: bot.True(),
+		},
+	})
+}
+
+func (s *Sender) SendText(ctx context.Context, chatID int64, text string) error {
+	return s.send(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   text,
+	})
+}
+
+func (s *Sender) Close() error {
+	s.ticker.Stop()
+	return nil
+}
+
+func (s *Sender) send(ctx context.Context, params *bot.SendMessageParams) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ticker.C:
+	}
+
+	_, err := s.client.SendMessage(ctx, params)
 	return err
 }
@@ -0,0 +1,153 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/background"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	addSearchCommand = "addsearch"
+	brandCommand     = "brand"
+	sellerCommand    = "seller"
+)
+
+type SourceAdder interface {
+	AddSearch(ctx context.Context, query string) (model.Category, error)
+	AddBrand(ctx context.Context, value string) (model.Category, error)
+	AddSeller(ctx context.Context, value string) (model.Category, error)
+}
+
+// sourceHandler subscribes chats to search queries, brands and sellers, they are crawled like categories.
+type sourceHandler struct {
+	logger log.Logger
+	worker *background.Worker
+
+	sourceAdder    SourceAdder
+	productUpdater ProductUpdater
+}
+
+func newSourceHandler(logger log.Logger, worker *background.Worker, sourceAdder SourceAdder, productUpdater ProductUpdater) *sourceHandler {
+	return &sourceHandler{
+		logger:         logger,
+		worker:         worker,
+		sourceAdder:    sourceAdder,
+		productUpdater: productUpdater,
+	}
+}
+
+func (h *sourceHandler) AddSearch(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddSearch")
+
+	chatID := update.Message.Chat.ID
+	query := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+addSearchCommand))
+	if query == "" {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /addsearch <поисковый запрос>, например: /addsearch льняное платье миди", "AddSearch")
+		return
+	}
+
+	h.addSource(ctx, b, chatID, "AddSearch", "Ищу товары по запросу", func(ctx context.Context) (model.Category, error) {
+		return h.sourceAdder.AddSearch(ctx, query)
+	})
+}
+
+func (h *sourceHandler) AddBrand(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddBrand")
+
+	chatID := update.Message.Chat.ID
+	value := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+brandCommand))
+	if value == "" {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /brand <название или id бренда>, например: /brand Zarina", "AddBrand")
+		return
+	}
+
+	h.addSource(ctx, b, chatID, "AddBrand", "Загружаю товары бренда", func(ctx context.Context) (model.Category, error) {
+		return h.sourceAdder.AddBrand(ctx, value)
+	})
+}
+
+func (h *sourceHandler) AddSeller(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddSeller")
+
+	chatID := update.Message.Chat.ID
+	value := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, "/"+sellerCommand))
+	if value == "" {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /seller <название или id продавца>, id есть в ссылке на магазин продавца", "AddSeller")
+		return
+	}
+
+	h.addSource(ctx, b, chatID, "AddSeller", "Загружаю товары продавца", func(ctx context.Context) (model.Category, error) {
+		return h.sourceAdder.AddSeller(ctx, value)
+	})
+}
+
+func (h *sourceHandler) addSource(
+	ctx context.Context,
+	b *bot.Bot,
+	chatID int64,
+	handlerName string,
+	startText string,
+	add func(ctx context.Context) (model.Category, error),
+) {
+	sendMessage(ctx, h.logger, b, chatID, startText+", это может занять пару минут ⏳", handlerName)
+
+	// the first crawl of a source walks all pages, it must not block the update handler
+	h.worker.Run(ctx, handlerName, func(ctx context.Context) error {
+		category, err := add(ctx)
+		if err != nil {
+			text := "К сожалению не удалось загрузить товары, попробуйте позже :С"
+			switch {
+			case errors.Is(err, model.ErrInvalidQuery):
+				text = "Запрос должен быть длиной от 2 до 100 символов"
+			case errors.Is(err, model.ErrInvalidCategory):
+				text = "Товары не найдены :С"
+			case errors.Is(err, model.ErrBrandNotFound):
+				text = "Бренд с таким названием не найден, попробуйте указать id бренда из ссылки WB"
+			case errors.Is(err, model.ErrSellerNotFound):
+				text = "Продавец с таким названием не найден, попробуйте указать id продавца из ссылки WB"
+			default:
+				h.logger.Error().Err(err).Str("handler", handlerName).Int64("chat_id", chatID).Msg("add source failed")
+			}
+
+			sendMessage(ctx, h.logger, b, chatID, text, handlerName)
+			return nil
+		}
+
+		if err = h.productUpdater.UpdateProducts(ctx, category); errors.Is(err, model.ErrCrawlInProgress) {
+			sendMessage(ctx, h.logger, b, chatID, "Товары по этому запросу уже загружаются, попробуйте через пару минут ⏳", handlerName)
+			return nil
+		}
+
+		if err != nil {
+			h.logger.Error().Err(err).Str("handler", handlerName).Str("category", category.Name).Msg("source crawl failed")
+			sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось загрузить товары, попробуйте позже :С", handlerName)
+			return nil
+		}
+
+		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("Товары «%s» загружены, выберите размер для отслеживания цен:", category.Title),
+			ReplyMarkup: &models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{{{
+					Text:         fmt.Sprintf("%s %s", category.Emoji, category.Title),
+					CallbackData: fmt.Sprintf("%s%d:%s:%s", trackingCategoriesURL, category.ID, category.Title, category.Emoji),
+				}}},
+			},
+		})
+		if err != nil {
+			h.logger.Error().Err(err).
+				Str("handler", handlerName).
+				Int64("chat_id", chatID).
+				Msg("failed send message")
+		}
+
+		return nil
+	})
+}
@@ -18,14 +18,16 @@ type DeleteTrackingRepository interface {
 }
 
 type startHandler struct {
-	logger     log.Logger
-	repository DeleteTrackingRepository
+	logger         log.Logger
+	repository     DeleteTrackingRepository
+	chatRepository ChatRepository
 }
 
-func NewStartHandlerOption(logger log.Logger, repository DeleteTrackingRepository) bot.Option {
+func NewStartHandlerOption(logger log.Logger, repository DeleteTrackingRepository, chatRepository ChatRepository) bot.Option {
 	handler := &startHandler{
-		logger:     logger,
-		repository: repository,
+		logger:         logger,
+		repository:     repository,
+		chatRepository: chatRepository,
 	}
 	return bot.WithDefaultHandler(handler.Handle)
 }
@@ -38,8 +40,15 @@ func (h *startHandler) Handle(ctx context.Context, b *bot.Bot, update *models.Up
 Вы можете управлять мной, отправляя следующие команды:
 
 /addtracking - добавляет отслеживание
+/addsearch - отслеживает цены по поисковому запросу
+/brand - отслеживает цены товаров бренда
+/seller - отслеживает цены товаров продавца
+/addnewarrivals - добавляет отслеживание новинок
+/addlowstock - сообщает о последних штуках в вашем размере
 /deletetracking - удаляет отслеживание
-/showtracking - показывает текущие настройки отслеживания`
+/showtracking - показывает текущие настройки отслеживания
+/region - выбирает регион доставки для цен и наличия
+/notifyback - сообщает о поступлении размера товара`
 
 	if update.Message == nil {
 		if chatMember := update.MyChatMember; chatMember != nil {
@@ -51,6 +60,10 @@ func (h *startHandler) Handle(ctx context.Context, b *bot.Bot, update *models.Up
 					if err := h.repository.DeleteTrackingSettingsByChat(ctx, chatID); err != nil {
 						h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed remove tracking settings")
 					}
+
+					if err := h.chatRepository.DeleteChat(ctx, chatID); err != nil {
+						h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed remove chat")
+					}
 				}
 			}
 		}
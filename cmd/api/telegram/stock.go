@@ -0,0 +1,180 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	notifyBackCommand = "notifyback"
+	stockSubscribeURL = "/stocksubscribe/"
+)
+
+var (
+	productURLRegexp     = regexp.MustCompile(`/catalog/(\d+)/`)
+	ozonProductURLRegexp = regexp.MustCompile(`ozon\.ru/product/(?:[^/?#]*-)?(\d+)`)
+)
+
+type StockRepository interface {
+	GetProductStock(ctx context.Context, chatID int64, marketplace model.Marketplace, externalID uint64) (model.ProductStock, error)
+	AddSubscription(ctx context.Context, subscription model.StockSubscription) error
+}
+
+type stockHandler struct {
+	logger          log.Logger
+	stockRepository StockRepository
+}
+
+func newStockHandler(logger log.Logger, stockRepository StockRepository) *stockHandler {
+	return &stockHandler{
+		logger:          logger,
+		stockRepository: stockRepository,
+	}
+}
+
+func (h *stockHandler) ShowUnavailableSizes(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowUnavailableSizes")
+
+	chatID := update.Message.Chat.ID
+	marketplace, productID, ok := parseProductID(strings.TrimPrefix(update.Message.Text, "/"+notifyBackCommand))
+	if !ok {
+		sendMessage(ctx, h.logger, b, chatID, "Использование: /notifyback <ссылка на товар или артикул>", "ShowUnavailableSizes")
+		return
+	}
+
+	product, err := h.stockRepository.GetProductStock(ctx, chatID, marketplace, productID)
+	if err != nil {
+		if errors.Is(err, model.ErrProductNotFound) {
+			sendMessage(ctx, h.logger, b, chatID, "К сожалению этот товар пока не отслеживается ботом :С", "ShowUnavailableSizes")
+			return
+		}
+
+		if errors.Is(err, model.ErrAmbiguousProduct) {
+			sendMessage(ctx, h.logger, b, chatID, "Товары с таким артикулом есть на нескольких маркетплейсах, пришлите ссылку на товар", "ShowUnavailableSizes")
+			return
+		}
+
+		h.logger.Error().Err(err).Str("handler", "ShowUnavailableSizes").Str("marketplace", string(marketplace)).Uint64("external_id", productID).Msg("get product stock failed")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению пока данный функционал недоступен, попробуйте позже :С", "ShowUnavailableSizes")
+		return
+	}
+
+	var row []models.InlineKeyboardButton
+	var rows [][]models.InlineKeyboardButton
+	for _, size := range product.Sizes {
+		if size.IsAvailable {
+			continue
+		}
+
+		row = append(row, models.InlineKeyboardButton{
+			Text:         size.Size,
+			CallbackData: fmt.Sprintf("%s%d:%d", stockSubscribeURL, product.ProductID, size.SizeID),
+		})
+
+		if len(row) == buttonsPerRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		sendMessage(ctx, h.logger, b, chatID, "Все размеры этого товара сейчас в наличии", "ShowUnavailableSizes")
+		return
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("%s\n\nВыберите размер, о поступлении которого нужно сообщить:", product.ProductName),
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		},
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "ShowUnavailableSizes").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
+func (h *stockHandler) AddSubscription(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "AddStockSubscription")
+
+	if update.CallbackQuery == nil {
+		h.logger.Error().Str("handler", "AddStockSubscription").Msg("callback query is empty")
+		return
+	}
+
+	data, isFound := strings.CutPrefix(update.CallbackQuery.Data, stockSubscribeURL)
+	if !isFound {
+		h.logger.Error().Str("handler", "AddStockSubscription").
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't extract data from callback query data")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	values := strings.Split(data, ":")
+	if len(values) < 2 {
+		h.logger.Error().Str("handler", "AddStockSubscription").Str("callback_data", update.CallbackQuery.Data).Msg("invalid callback data")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось добавить подписку, попробуйте позже :С", "AddStockSubscription")
+		return
+	}
+
+	productID, pErr := strconv.ParseUint(values[0], 10, 64)
+	sizeID, sErr := strconv.ParseUint(values[1], 10, 64)
+	if err := errors.Join(pErr, sErr); err != nil {
+		h.logger.Error().Err(err).Str("handler", "AddStockSubscription").
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't parse product_id or size_id from callback query data")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось добавить подписку, попробуйте позже :С", "AddStockSubscription")
+		return
+	}
+
+	subscription := model.StockSubscription{
+		ChatID:    chatID,
+		ProductID: productID,
+		SizeID:    sizeID,
+	}
+
+	if err := h.stockRepository.AddSubscription(ctx, subscription); err != nil {
+		h.logger.Error().Err(err).Str("handler", "AddStockSubscription").Int64("chat_id", chatID).Msg("failed add stock subscription")
+		sendMessage(ctx, h.logger, b, chatID, "К сожалению не удалось добавить подписку, попробуйте позже :С", "AddStockSubscription")
+		return
+	}
+
+	sendMessage(ctx, h.logger, b, chatID, "Готово! Мы сообщим, когда размер снова появится в наличии", "AddStockSubscription")
+}
+
+// parseProductID returns the marketplace id of the product from a link or a bare id,
+// the marketplace is known from links only.
+func parseProductID(value string) (model.Marketplace, uint64, bool) {
+	var marketplace model.Marketplace
+
+	value = strings.TrimSpace(value)
+	if matches := ozonProductURLRegexp.FindStringSubmatch(value); len(matches) == 2 {
+		marketplace, value = model.MarketplaceOzon, matches[1]
+	} else if matches = productURLRegexp.FindStringSubmatch(value); len(matches) == 2 {
+		marketplace, value = model.MarketplaceWildberries, matches[1]
+	}
+
+	productID, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return marketplace, productID, true
+}
@@ -0,0 +1,30 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/cmd/api/model"
+)
+
+func TestParseProductID(t *testing.T) {
+	tests := []struct {
+		value       string
+		marketplace model.Marketplace
+		productID   uint64
+		ok          bool
+	}{
+		{value: " 215430917", productID: 215430917, ok: true},
+		{value: " https://www.wildberries.ru/catalog/215430917/detail.aspx?size=1", marketplace: model.MarketplaceWildberries, productID: 215430917, ok: true},
+		{value: " https://www.ozon.ru/product/1234567890/", marketplace: model.MarketplaceOzon, productID: 1234567890, ok: true},
+		{value: " https://www.ozon.ru/product/plate-midi-zarina-1234567890/?at=abc", marketplace: model.MarketplaceOzon, productID: 1234567890, ok: true},
+		{value: " https://www.ozon.ru/category/platya-7502/"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		marketplace, productID, ok := parseProductID(tt.value)
+		if marketplace != tt.marketplace || productID != tt.productID || ok != tt.ok {
+			t.Errorf("%q: expected %q %d %t, got %q %d %t", tt.value, tt.marketplace, tt.productID, tt.ok, marketplace, productID, ok)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 const (
 	trackingCategoriesURL = "/trackingcategories/"
 	showDiffPricesURL     = "/showdiffprices/"
+	showPriceTypesURL     = "/pricetype/"
 	addTrackingURL        = "/addtracking/"
 	deleteTrackingURL     = "/deletetracking/"
 
@@ -24,7 +25,7 @@ const (
 )
 
 type CategoryRepository interface {
-	GetCategories(ctx context.Context) ([]model.Category, error)
+	GetCategoryMenu(ctx context.Context, parentID uint64) ([]model.CategoryNode, error)
 }
 
 type SizeRepository interface {
@@ -35,7 +36,7 @@ type SizeRepository interface {
 type TrackingRepository interface {
 	AddTracking(ctx context.Context, settings model.TrackingSettings) error
 	GetTrackingSettingsInfo(ctx context.Context, chatID int64) ([]model.TrackingSettingsInfo, error)
-	DeleteTrackingSettings(ctx context.Context, chatID int64, sizeID uint64, categoryID uint64) error
+	DeleteTrackingSettings(ctx context.Context, chatID int64, sizeID uint64, categoryID uint64, trackingType model.TrackingType) error
 }
 
 type trackingHandler struct {
@@ -63,45 +64,7 @@ func newTrackingHandler(
 func (h *trackingHandler) ShowCategoryTrackingOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer recovery(h.logger, "ShowCategoryTrackingOptions")
 
-	categories, err := h.categoryRepository.GetCategories(ctx)
-	if err != nil || len(categories) == 0 {
-		h.logger.Error().Err(err).Str("handler", "ShowCategoryTrackingOptions").Msg("get categories failed")
-
-		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "К сожалению пока данный функционал недоступен, попробуйте позже :С",
-		})
-		if err != nil {
-			h.logger.Error().Err(err).
-				Str("handler", "ShowCategoryTrackingOptions").
-				Int64("chat_id", update.Message.Chat.ID).
-				Msg("failed send message")
-		}
-
-		return
-	}
-
-	var rows [][]models.InlineKeyboardButton
-	for _, category := range categories {
-		rows = append(rows, []models.InlineKeyboardButton{{
-			Text:         fmt.Sprintf("%s %s", category.Emoji, category.Title),
-			CallbackData: fmt.Sprintf("%s%d:%s:%s", trackingCategoriesURL, category.ID, category.Title, category.Emoji),
-		}})
-	}
-
-	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: update.Message.Chat.ID,
-		Text:   "Выберите категорию товара для отслеживания:",
-		ReplyMarkup: &models.InlineKeyboardMarkup{
-			InlineKeyboard: rows,
-		},
-	})
-	if err != nil {
-		h.logger.Error().Err(err).
-			Str("handler", "ShowCategoryTrackingOptions").
-			Int64("chat_id", update.Message.Chat.ID).
-			Msg("failed send message")
-	}
+	showCategoryMenu(ctx, b, h.logger, h.categoryRepository, update.Message.Chat.ID, trackingMenuFlow, 0, "ShowCategoryTrackingOptions")
 }
 
 func (h *trackingHandler) ShowSizeTrackingOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -246,34 +209,34 @@ func (h *trackingHandler) ShowDiffPriceOptions(ctx context.Context, b *bot.Bot,
 		ReplyMarkup: &models.InlineKeyboardMarkup{
 			InlineKeyboard: [][]models.InlineKeyboardButton{
 				{
-					{Text: "5%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "5", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "10%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "10", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "15%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "15", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "20%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "20", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "5%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "5", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "10%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "10", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "15%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "15", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "20%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "20", sizeID, categoryTitle, categoryEmoji, categoryID)},
 				},
 				{
-					{Text: "25%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "25", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "30%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "30", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "35%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "35", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "40%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "40", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "25%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "25", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "30%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "30", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "35%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "35", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "40%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "40", sizeID, categoryTitle, categoryEmoji, categoryID)},
 				},
 				{
-					{Text: "45%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "45", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "50%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "50", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "55%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "55", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "60%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "60", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "45%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "45", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "50%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "50", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "55%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "55", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "60%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "60", sizeID, categoryTitle, categoryEmoji, categoryID)},
 				},
 				{
-					{Text: "65%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "65", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "70%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "70", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "75%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "75", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "80%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "80", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "65%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "65", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "70%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "70", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "75%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "75", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "80%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "80", sizeID, categoryTitle, categoryEmoji, categoryID)},
 				},
 				{
-					{Text: "85%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "85", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "90%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "90", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "95%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "95", sizeID, categoryTitle, categoryEmoji, categoryID)},
-					{Text: "100%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", addTrackingURL, "100", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "85%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "85", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "90%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "90", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "95%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "95", sizeID, categoryTitle, categoryEmoji, categoryID)},
+					{Text: "100%", CallbackData: fmt.Sprintf("%s%s/%d:%s:%s:%d", showPriceTypesURL, "100", sizeID, categoryTitle, categoryEmoji, categoryID)},
 				},
 			},
 		},
@@ -286,6 +249,42 @@ func (h *trackingHandler) ShowDiffPriceOptions(ctx context.Context, b *bot.Bot,
 	}
 }
 
+func (h *trackingHandler) ShowPriceTypeOptions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	defer recovery(h.logger, "ShowPriceTypeOptions")
+
+	if update.CallbackQuery == nil {
+		h.logger.Error().Str("handler", "ShowPriceTypeOptions").Msg("callback query is empty")
+		return
+	}
+
+	data, isFound := strings.CutPrefix(update.CallbackQuery.Data, showPriceTypesURL)
+	if !isFound {
+		h.logger.Error().Str("handler", "ShowPriceTypeOptions").
+			Str("callback_data", update.CallbackQuery.Data).
+			Msg("can't extract data from callback query data")
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatID,
+		Text:   "Какую цену отслеживать?",
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{{Text: "Итоговую цену", CallbackData: fmt.Sprintf("%s%s:%d", addTrackingURL, data, model.PriceTypeTotal)}},
+				{{Text: "Цену товара без доставки", CallbackData: fmt.Sprintf("%s%s:%d", addTrackingURL, data, model.PriceTypeProduct)}},
+			},
+		},
+	})
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("handler", "ShowPriceTypeOptions").
+			Int64("chat_id", chatID).
+			Msg("failed send message")
+	}
+}
+
 func (h *trackingHandler) AddTracking(ctx context.Context, b *bot.Bot, update *models.Update) {
 	defer recovery(h.logger, "AddTrackingSize")
 
@@ -369,11 +368,25 @@ func (h *trackingHandler) AddTracking(ctx context.Context, b *bot.Bot, update *m
 		return
 	}
 
+	priceType := model.PriceTypeTotal
+	if len(trackingParams) > 4 {
+		priceTypeValue, pErr := strconv.ParseUint(trackingParams[4], 10, 8)
+		if pErr != nil {
+			h.logger.Error().Str("handler", "AddTracking").
+				Str("callback_data", update.CallbackQuery.Data).
+				Msg("can't parse price type from callback query data")
+			return
+		}
+		priceType = model.PriceType(priceTypeValue)
+	}
+
 	trackingSettings := model.TrackingSettings{
 		ChatID:     chatID,
 		SizeID:     sizeID,
 		CategoryID: categoryID,
+		Type:       model.TrackingTypePriceDrop,
 		DiffValue:  diffPercent,
+		PriceType:  priceType,
 	}
 
 	if err = h.trackingRepository.AddTracking(ctx, trackingSettings); err != nil {
@@ -394,7 +407,7 @@ func (h *trackingHandler) AddTracking(ctx context.Context, b *bot.Bot, update *m
 	const messageText = `Вы добавили настройки отслеживания для следующих параметров:
 <b>Категория</b>: <i>%s</i> %s
 <b>Размер</b>: <i>%s</i> 📏
-<b>Снижение цены</b>: <i>%d%%</i> ⬇️`
+<b>Снижение цены</b>: <i>%d%%%s</i> ⬇️`
 
 	sizeData, err := h.sizeRepository.GetSizeCategoryInfo(ctx, sizeID, categoryID)
 	if err != nil {
@@ -404,7 +417,7 @@ func (h *trackingHandler) AddTracking(ctx context.Context, b *bot.Bot, update *m
 
 	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    chatID,
-		Text:      fmt.Sprintf(messageText, categoryTitle, categoryEmoji, sizeData.Name, diffPercent),
+		Text:      fmt.Sprintf(messageText, categoryTitle, categoryEmoji, sizeData.Name, diffPercent, formatPriceType(priceType)),
 		ParseMode: models.ParseModeHTML,
 	})
 	if err != nil {
@@ -453,14 +466,32 @@ func (h *trackingHandler) ShowTrackingSettings(ctx context.Context, b *bot.Bot,
 
 	const messageText = `<b>Категория</b>: <i>%s</i> %s
 <b>Размер</b>: <i>%s</i> 📏
-<b>Снижение цены</b>: <i>%d%%</i> ⬇️`
+<b>Снижение цены</b>: <i>%d%%%s</i> ⬇️`
+
+	const newArrivalsText = `<b>Категория</b>: <i>%s</i> %s
+<b>Размер</b>: <i>%s</i> 📏
+<b>Новинки</b>: <i>%s</i> 🆕`
+
+	const lowStockText = `<b>Категория</b>: <i>%s</i> %s
+<b>Размер</b>: <i>%s</i> 📏
+<b>Последние штуки</b>: <i>меньше %d шт., %s</i> ⏳`
 
 	var sb strings.Builder
 	sb.WriteString("Ваши текущие настройки отслеживания:")
 
 	for _, settings := range trackingSettings {
 		sb.WriteString("\n\n")
-		sb.WriteString(fmt.Sprintf(messageText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.DiffPercent))
+
+		switch settings.Type {
+		case model.TrackingTypeNewArrivals:
+			sb.WriteString(fmt.Sprintf(newArrivalsText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, formatMaxPrice(settings.MaxPrice)))
+			continue
+		case model.TrackingTypeLowStock:
+			sb.WriteString(fmt.Sprintf(lowStockText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.StockThreshold, formatMaxPrice(settings.MaxPrice)))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf(messageText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.DiffPercent, formatPriceType(settings.PriceType)))
 	}
 
 	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
@@ -513,12 +544,22 @@ func (h *trackingHandler) ShowDeleteTrackingSettings(ctx context.Context, b *bot
 	}
 
 	const msgText = "❌   %s %s %s 📏 %d%% ⬇️"
+	const newArrivalsMsgText = "❌   %s %s %s 📏 🆕 %s"
+	const lowStockMsgText = "❌   %s %s %s 📏 ⏳ <%d %s"
 
 	var rows [][]models.InlineKeyboardButton
 	for _, settings := range trackingSettings {
+		text := fmt.Sprintf(msgText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.DiffPercent)
+		switch settings.Type {
+		case model.TrackingTypeNewArrivals:
+			text = fmt.Sprintf(newArrivalsMsgText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, formatMaxPrice(settings.MaxPrice))
+		case model.TrackingTypeLowStock:
+			text = fmt.Sprintf(lowStockMsgText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.StockThreshold, formatMaxPrice(settings.MaxPrice))
+		}
+
 		rows = append(rows, []models.InlineKeyboardButton{{
-			Text:         fmt.Sprintf(msgText, settings.CategoryTitle, settings.CategoryEmoji, settings.Size, settings.DiffPercent),
-			CallbackData: fmt.Sprintf("%s%d:%d", deleteTrackingURL, settings.CategoryID, settings.SizeID),
+			Text:         text,
+			CallbackData: fmt.Sprintf("%s%d:%d:%d", deleteTrackingURL, settings.CategoryID, settings.SizeID, settings.Type),
 		}})
 	}
 
@@ -608,7 +649,19 @@ func (h *trackingHandler) DeleteTrackingSettings(ctx context.Context, b *bot.Bot
 		return
 	}
 
-	if err = h.trackingRepository.DeleteTrackingSettings(ctx, chatID, sizeID, categoryID); err != nil {
+	trackingType := model.TrackingTypePriceDrop
+	if len(values) > 2 {
+		typeValue, tErr := strconv.ParseUint(values[2], 10, 8)
+		if tErr != nil {
+			h.logger.Error().Str("handler", "DeleteTrackingSettings").
+				Str("callback_data", update.CallbackQuery.Data).
+				Msg("can't parse tracking type from callback query data")
+			return
+		}
+		trackingType = model.TrackingType(typeValue)
+	}
+
+	if err = h.trackingRepository.DeleteTrackingSettings(ctx, chatID, sizeID, categoryID, trackingType); err != nil {
 		h.logger.Error().Err(err).
 			Str("handler", "DeleteTrackingSettings").
 			Int64("chat_id", chatID).
@@ -637,3 +690,17 @@ func (h *trackingHandler) DeleteTrackingSettings(ctx context.Context, b *bot.Bot
 			Msg("failed send message")
 	}
 }
+
+func formatPriceType(priceType model.PriceType) string {
+	if priceType == model.PriceTypeProduct {
+		return ", цена товара"
+	}
+	return ""
+}
+
+func formatMaxPrice(maxPrice model.Money) string {
+	if maxPrice == 0 {
+		return "любая цена"
+	}
+	return fmt.Sprintf("до %d ₽", maxPrice.Rubles())
+}
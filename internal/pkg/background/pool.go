@@ -0,0 +1,78 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type Pool struct {
+	logger log.Logger
+	wg     sync.WaitGroup
+	slots  chan struct{}
+}
+
+func NewPool(logger log.Logger, size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Pool{
+		logger: logger,
+		slots:  make(chan struct{}, size),
+	}
+}
+
+func (p *Pool) Run(ctx context.Context, name string, handler HandlerFunc) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p.slots <- struct{}{}:
+	}
+
+	p.wg.Add(1)
+
+	go func(ctx context.Context, handler HandlerFunc) {
+		defer p.wg.Done()
+		defer func() { <-p.slots }()
+		defer p.recover(name)
+		start := time.Now()
+
+		if err := handler(ctx); err != nil {
+			if errors.Is(err, context.Canceled) {
+				p.logger.Info().Str("task", name).Msg("pool task canceled")
+				return
+			}
+			p.logger.Error().Err(err).Str("task", name).Msg("pool task error")
+		}
+
+		p.logger.Debug().Str("task", name).Str("duration", time.Since(start).String()).Send()
+	}(ctx, handler)
+
+	return nil
+}
+
+func (p *Pool) Wait() {
+	p.wg.Wait()
+}
+
+func (p *Pool) recover(name string) {
+	if rvr := recover(); rvr != nil {
+		event := p.logger.Error().
+			Str("task", name).
+			Str("stack", string(debug.Stack()))
+
+		err, ok := rvr.(error)
+		if ok {
+			event = event.Err(err)
+		} else {
+			event = event.Interface("panic", rvr)
+		}
+
+		event.Msg("pool task recovered from panic")
+	}
+}
@@ -0,0 +1,128 @@
+// Package mysqltest gives tests a migrated database on the mysql server of MYSQL_TEST_DSN, tests are skipped without it.
+package mysqltest
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/database/mysql"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+// DSNEnv names the variable with the connection string of a user allowed to create and drop databases,
+// e.g. root:root@tcp(127.0.0.1:3306)/ for the dev infrastructure.
+const DSNEnv = "MYSQL_TEST_DSN"
+
+var databaseCounter atomic.Uint64
+
+// New creates a database of its own for the test, applies the up migrations of migrations/mysql and drops it on cleanup.
+func New(t testing.TB) *mysql.Connection {
+	t.Helper()
+
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", DSNEnv)
+	}
+
+	config, err := driver.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("mysqltest: parse %s: %v", DSNEnv, err)
+	}
+	config.DBName = ""
+	config.MultiStatements = true
+
+	server, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		t.Fatalf("mysqltest: open server connection: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = server.Close()
+	})
+
+	name := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), databaseCounter.Add(1))
+	if _, err = server.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("mysqltest: create database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, dErr := server.Exec("DROP DATABASE " + name); dErr != nil {
+			t.Errorf("mysqltest: drop database %s: %v", name, dErr)
+		}
+	})
+
+	config.DBName = name
+	conn, err := mysql.NewConnection(log.NewNop(), mysql.Config{
+		ConnectionString:   config.FormatDSN(),
+		MaxOpenConnections: 5,
+		MaxIdleConnections: 5,
+	})
+	if err != nil {
+		t.Fatalf("mysqltest: connect: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	migrations, err := upMigrations()
+	if err != nil {
+		t.Fatalf("mysqltest: %v", err)
+	}
+
+	for _, migration := range migrations {
+		query, rErr := os.ReadFile(migration)
+		if rErr != nil {
+			t.Fatalf("mysqltest: read migration: %v", rErr)
+		}
+
+		if _, err = conn.Exec(string(query)); err != nil {
+			t.Fatalf("mysqltest: apply %s: %v", filepath.Base(migration), err)
+		}
+	}
+
+	return conn
+}
+
+// upMigrations finds migrations/mysql in the module root above the test package and orders its up files by number.
+func upMigrations() ([]string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, err = os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("go.mod not found above the test package")
+		}
+		dir = parent
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "migrations", "mysql", "*-up.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return migrationNumber(files[i]) < migrationNumber(files[j])
+	})
+
+	return files, nil
+}
+
+func migrationNumber(path string) int {
+	number, _, _ := strings.Cut(filepath.Base(path), "-")
+	result, _ := strconv.Atoi(number)
+	return result
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	CassetteModeRecord = "record"
+	CassetteModeReplay = "replay"
+)
+
+var ErrCassetteMiss = errors.New("no recorded interaction for request")
+
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+type CassetteRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query"`
+}
+
+type CassetteResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	RawBody string            `json:"raw_body,omitempty"`
+}
+
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type Cassette struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette read %s error: %w", path, err)
+	}
+
+	var cassette Cassette
+	if err = json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("cassette decode %s error: %w", path, err)
+	}
+
+	return &cassette, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette encode error: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cassette create dir error: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("cassette write %s error: %w", path, err)
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("cassette rename %s error: %w", path, err)
+	}
+
+	return nil
+}
+
+func newCassetteRequest(request *http.Request) CassetteRequest {
+	return CassetteRequest{
+		Method: request.Method,
+		Path:   request.URL.Path,
+		// Encode sorts keys so the match does not depend on parameter order
+		Query: request.URL.Query().Encode(),
+	}
+}
+
+type RecordTransport struct {
+	logger log.Logger
+	path   string
+	next   http.RoundTripper
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecordTransport appends to an existing cassette at path so recordings can be made over several runs.
+func NewRecordTransport(logger log.Logger, path string, next http.RoundTripper) (*RecordTransport, error) {
+	cassette := &Cassette{}
+	if _, err := os.Stat(path); err == nil {
+		if cassette, err = LoadCassette(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &RecordTransport{
+		logger:   logger,
+		path:     path,
+		next:     next,
+		cassette: cassette,
+	}, nil
+}
+
+func (t *RecordTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette read response body error: %w", err)
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := CassetteResponse{
+		Status:  response.StatusCode,
+		Headers: make(map[string]string),
+	}
+
+	for _, name := range recordedHeaders {
+		if value := response.Header.Get(name); value != "" {
+			recorded.Headers[name] = value
+		}
+	}
+
+	if len(body) > 0 {
+		if json.Valid(body) {
+			recorded.Body = body
+		} else {
+			recorded.RawBody = string(body)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, CassetteInteraction{
+		Request:  newCassetteRequest(request),
+		Response: recorded,
+	})
+
+	return response, nil
+}
+
+// Close writes the recorded interactions once, rewriting the whole cassette per request is quadratic on long crawls.
+func (t *RecordTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.cassette.Save(t.path); err != nil {
+		return err
+	}
+
+	t.logger.Info().Str("path", t.path).Int("interactions", len(t.cassette.Interactions)).Msg("cassette saved")
+	return nil
+}
+
+type ReplayTransport struct {
+	mu           sync.Mutex
+	interactions []CassetteInteraction
+	used         []bool
+}
+
+func NewReplayTransport(cassette *Cassette) *ReplayTransport {
+	return &ReplayTransport{
+		interactions: cassette.Interactions,
+		used:         make([]bool, len(cassette.Interactions)),
+	}
+}
+
+// RoundTrip serves recorded interactions in order; once all matching ones are used the last one is repeated.
+func (t *ReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		_ = request.Body.Close()
+	}
+
+	key := newCassetteRequest(request)
+
+	t.mu.Lock()
+	index := -1
+	for i, interaction := range t.interactions {
+		if interaction.Request != key {
+			continue
+		}
+
+		index = i
+		if !t.used[i] {
+			t.used[i] = true
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s %s?%s", ErrCassetteMiss, key.Method, key.Path, key.Query)
+	}
+
+	recorded := t.interactions[index].Response
+
+	body := []byte(recorded.Body)
+	if recorded.RawBody != "" {
+		body = []byte(recorded.RawBody)
+	}
+
+	header := make(http.Header, len(recorded.Headers))
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+func newCassetteTransport(logger log.Logger, config CassetteConfig, next http.RoundTripper) (http.RoundTripper, error) {
+	switch config.Mode {
+	case "":
+		return next, nil
+	case CassetteModeRecord:
+		return NewRecordTransport(logger, config.Path, next)
+	case CassetteModeReplay:
+		cassette, err := LoadCassette(config.Path)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayTransport(cassette), nil
+	default:
+		return nil, fmt.Errorf("cassette unknown mode %q", config.Mode)
+	}
+}
@@ -0,0 +1,109 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("page") == "2" {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte("too many requests"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"page":"` + r.URL.Query().Get("page") + `"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewRecordTransport(log.NewNop(), path, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recordClient := &http.Client{Transport: recorder}
+	for _, query := range []string{"?cat=1&page=1", "?cat=1&page=2"} {
+		response, rErr := recordClient.Get(server.URL + "/catalog" + query)
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}
+
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the cassette written on close only, got %v", err)
+	}
+
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+
+	replayClient := &http.Client{Transport: NewReplayTransport(cassette)}
+
+	tests := []struct {
+		query      string
+		status     int
+		body       string
+		retryAfter string
+	}{
+		{query: "?page=1&cat=1", status: http.StatusOK, body: `{"page":"1"}`},
+		{query: "?page=2&cat=1", status: http.StatusTooManyRequests, body: "too many requests", retryAfter: "3"},
+	}
+
+	for _, tt := range tests {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://offline.invalid/catalog"+tt.query, nil)
+		response, rErr := replayClient.Do(request)
+		if rErr != nil {
+			t.Fatalf("replay %s: %v", tt.query, rErr)
+		}
+
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+
+		// json bodies are stored indented inside the cassette
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, body) == nil {
+			body = compacted.Bytes()
+		}
+
+		if response.StatusCode != tt.status || string(body) != tt.body || response.Header.Get("Retry-After") != tt.retryAfter {
+			t.Errorf("replay %s: unexpected response %d %q %q", tt.query, response.StatusCode, body, response.Header.Get("Retry-After"))
+		}
+	}
+
+	if requests != 2 {
+		t.Errorf("replay must not reach the server, got %d requests", requests)
+	}
+
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://offline.invalid/catalog?cat=1&page=3", nil)
+	if _, err = NewReplayTransport(cassette).RoundTrip(request); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("expected cassette miss, got %v", err)
+	}
+}
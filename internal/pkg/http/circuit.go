@@ -0,0 +1,205 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type CircuitBreaker struct {
+	logger log.Logger
+	name   string
+	config CircuitBreakerConfig
+
+	mu                sync.Mutex
+	state             CircuitState
+	failures          uint
+	openedAt          time.Time
+	halfOpenInFlight  uint
+	halfOpenSuccesses uint
+	// generation changes with every state, verdicts of requests admitted in another state are ignored
+	generation uint64
+}
+
+func NewCircuitBreaker(logger log.Logger, name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		logger: logger,
+		name:   name,
+		config: config,
+	}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Allow admits a request and returns the generation its verdict has to be reported with.
+func (b *CircuitBreaker) Allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.config.CoolDown {
+			return 0, ErrCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.halfOpenInFlight >= max(b.config.HalfOpenRequests, 1) {
+			return 0, ErrCircuitOpen
+		}
+		b.halfOpenInFlight++
+	}
+
+	return b.generation, nil
+}
+
+func (b *CircuitBreaker) Success(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case CircuitClosed:
+		b.failures = 0
+	case CircuitHalfOpen:
+		if b.halfOpenInFlight > 0 {
+			b.halfOpenInFlight--
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= max(b.config.HalfOpenRequests, 1) {
+			b.setState(CircuitClosed)
+		}
+	}
+}
+
+func (b *CircuitBreaker) Failure(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case CircuitClosed:
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.setState(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		b.setState(CircuitOpen)
+	}
+}
+
+// Cancel releases a half-open slot of a request that ended without a verdict, e.g. canceled by context.
+func (b *CircuitBreaker) Cancel(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation == b.generation && b.state == CircuitHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+}
+
+func (b *CircuitBreaker) setState(state CircuitState) {
+	b.logger.Warn().
+		Str("circuit", b.name).
+		Str("from", b.state.String()).
+		Str("to", state.String()).
+		Msg("circuit breaker state changed")
+
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+
+	if state == CircuitOpen {
+		b.openedAt = time.Now()
+	}
+}
+
+type circuitBreakerTransport struct {
+	logger log.Logger
+	config CircuitBreakerConfig
+	next   http.RoundTripper
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+func NewCircuitBreakerTransport(logger log.Logger, config CircuitBreakerConfig, next http.RoundTripper) http.RoundTripper {
+	return &circuitBreakerTransport{
+		logger:   logger,
+		config:   config,
+		next:     next,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+func (t *circuitBreakerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	breaker := t.breaker(request.URL.Host)
+	generation, err := breaker.Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.next.RoundTrip(request)
+	switch {
+	case request.Context().Err() != nil:
+		breaker.Cancel(generation)
+	case err != nil || slices.Contains(t.config.FailureStatuses, response.StatusCode):
+		breaker.Failure(generation)
+	default:
+		breaker.Success(generation)
+	}
+
+	return response, err
+}
+
+func (t *circuitBreakerTransport) breaker(host string) *CircuitBreaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	breaker, ok := t.breakers[host]
+	if !ok {
+		breaker = NewCircuitBreaker(t.logger, host, t.config)
+		t.breakers[host] = breaker
+	}
+
+	return breaker
+}
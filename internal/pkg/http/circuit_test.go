@@ -0,0 +1,151 @@
+package http
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+func newTestCircuitBreaker(halfOpenRequests uint) *CircuitBreaker {
+	return NewCircuitBreaker(log.NewNop(), "test", CircuitBreakerConfig{
+		FailureThreshold: 2,
+		CoolDown:         time.Hour,
+		HalfOpenRequests: halfOpenRequests,
+	})
+}
+
+func allow(t *testing.T, breaker *CircuitBreaker) uint64 {
+	t.Helper()
+
+	generation, err := breaker.Allow()
+	if err != nil {
+		t.Fatalf("expected request allowed in state %s, got %v", breaker.State(), err)
+	}
+
+	return generation
+}
+
+// openAndCoolDown trips the breaker and moves the open time back so the next Allow goes half-open.
+func openAndCoolDown(t *testing.T, breaker *CircuitBreaker) {
+	t.Helper()
+
+	for i := uint(0); i < breaker.config.FailureThreshold; i++ {
+		breaker.Failure(allow(t, breaker))
+	}
+
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected open, got %s", breaker.State())
+	}
+
+	breaker.mu.Lock()
+	breaker.openedAt = time.Now().Add(-breaker.config.CoolDown)
+	breaker.mu.Unlock()
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	breaker := newTestCircuitBreaker(1)
+
+	breaker.Failure(allow(t, breaker))
+	breaker.Success(allow(t, breaker))
+	breaker.Failure(allow(t, breaker))
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("a success must reset failures, got %s", breaker.State())
+	}
+
+	breaker.Failure(allow(t, breaker))
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("expected open, got %s", breaker.State())
+	}
+
+	if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit error during cool down, got %v", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name             string
+		halfOpenRequests uint
+		verdicts         []string
+		state            CircuitState
+	}{
+		{name: "probe succeeded", halfOpenRequests: 1, verdicts: []string{"success"}, state: CircuitClosed},
+		{name: "probe failed", halfOpenRequests: 1, verdicts: []string{"failure"}, state: CircuitOpen},
+		{name: "probe canceled", halfOpenRequests: 1, verdicts: []string{"cancel"}, state: CircuitHalfOpen},
+		{name: "one of two probes", halfOpenRequests: 2, verdicts: []string{"success"}, state: CircuitHalfOpen},
+		{name: "both probes", halfOpenRequests: 2, verdicts: []string{"success", "success"}, state: CircuitClosed},
+		{name: "second probe failed", halfOpenRequests: 2, verdicts: []string{"success", "failure"}, state: CircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := newTestCircuitBreaker(tt.halfOpenRequests)
+			openAndCoolDown(t, breaker)
+
+			generations := make([]uint64, 0, tt.halfOpenRequests)
+			for i := uint(0); i < tt.halfOpenRequests; i++ {
+				generations = append(generations, allow(t, breaker))
+			}
+
+			if _, err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+				t.Fatalf("expected probes limited to %d, got %v", tt.halfOpenRequests, err)
+			}
+
+			for i, verdict := range tt.verdicts {
+				switch verdict {
+				case "success":
+					breaker.Success(generations[i])
+				case "failure":
+					breaker.Failure(generations[i])
+				case "cancel":
+					breaker.Cancel(generations[i])
+				}
+			}
+
+			if breaker.State() != tt.state {
+				t.Fatalf("expected %s, got %s", tt.state, breaker.State())
+			}
+
+			if tt.verdicts[0] == "cancel" {
+				allow(t, breaker)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleVerdicts(t *testing.T) {
+	for _, halfOpenRequests := range []uint{1, 2} {
+		breaker := newTestCircuitBreaker(halfOpenRequests)
+
+		// admitted while closed, finished after the breaker went half-open
+		stale := allow(t, breaker)
+		openAndCoolDown(t, breaker)
+		probe := allow(t, breaker)
+
+		breaker.Success(stale)
+		breaker.Cancel(stale)
+		if breaker.State() != CircuitHalfOpen {
+			t.Fatalf("stale success must not close the circuit, got %s", breaker.State())
+		}
+
+		breaker.Failure(stale)
+		if breaker.State() != CircuitHalfOpen {
+			t.Fatalf("stale failure must not reopen the circuit, got %s", breaker.State())
+		}
+
+		breaker.Success(probe)
+		if halfOpenRequests == 1 && breaker.State() != CircuitClosed {
+			t.Fatalf("expected the real probe to close the circuit, got %s", breaker.State())
+		}
+
+		if halfOpenRequests == 2 {
+			// the freed slot is still usable, the in flight counter did not wrap
+			breaker.Success(allow(t, breaker))
+			if breaker.State() != CircuitClosed {
+				t.Fatalf("expected closed after two probes, got %s", breaker.State())
+			}
+		}
+	}
+}
@@ -1,27 +1,120 @@
 package http
 
 import (
+	"context"
 	"net"
 	"net/http"
+	"net/url"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/ratelimit"
 )
 
-func NewClient(config ClientConfig) *http.Client {
-	return &http.Client{
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   config.DialTimeout,
-				KeepAlive: config.DialKeepAlive,
-			}).DialContext,
+// Client is the shared http client, it keeps the proxy pool and the rate limiter to report their stats.
+type Client struct {
+	*http.Client
+
+	logger  log.Logger
+	pool    *ProxyPool
+	limiter *ratelimit.Transport
+	// recorder is set in cassette record mode, the cassette is written on Close
+	recorder *RecordTransport
+}
+
+func NewClient(logger log.Logger, config ClientConfig) (*Client, error) {
+	client := &Client{logger: logger}
+
+	var transport http.RoundTripper
+	if len(config.Proxy.URLs) > 0 || len(config.Profiles) > 0 {
+		pool, err := NewProxyPool(logger, config)
+		if err != nil {
+			return nil, err
+		}
+		client.pool = pool
+		transport = pool
+	} else {
+		transport = newTransport(config, http.ProxyFromEnvironment)
+	}
+
+	transport, err := newCassetteTransport(logger, config.Cassette, transport)
+	if err != nil {
+		return nil, err
+	}
+	client.recorder, _ = transport.(*RecordTransport)
+
+	if config.RateLimit.RequestsPerSecond > 0 || config.RateLimit.PerHostRequestsPerSecond > 0 {
+		client.limiter = ratelimit.NewTransport(logger, config.RateLimit, transport)
+		transport = client.limiter
+	}
+
+	if config.CircuitBreaker.FailureThreshold > 0 {
+		transport = NewCircuitBreakerTransport(logger, config.CircuitBreaker, transport)
+	}
+
+	client.Client = &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}
+
+	return client, nil
+}
+
+// LogStats logs the rate limiter waits and the counters of every proxy, it runs periodically
+// so throttling by the limiter can be told apart from ejections and dead proxies.
+func (c *Client) LogStats(_ context.Context) error {
+	if c.limiter != nil {
+		stats := c.limiter.Stats()
+		c.logger.Info().
+			Uint64("requests", stats.Requests).
+			Uint64("delayed", stats.Delayed).
+			Str("total_wait", stats.TotalWait.String()).
+			Str("max_wait", stats.MaxWait.String()).
+			Str("average_delay", stats.AverageDelay.String()).
+			Msg("rate limiter stats")
+	}
+
+	if c.pool == nil {
+		return nil
+	}
+
+	for _, stats := range c.pool.Stats() {
+		c.logger.Info().
+			Str("proxy", stats.URL).
+			Float64("score", stats.Score).
+			Bool("ejected", stats.Ejected).
+			Uint64("requests", stats.Requests).
+			Uint64("failures", stats.Failures).
+			Uint64("throttled", stats.Throttled).
+			Uint64("ejections", stats.Ejections).
+			Msg("proxy stats")
+	}
+
+	return nil
+}
+
+// Close saves the cassette in record mode.
+func (c *Client) Close() error {
+	if c.recorder == nil {
+		return nil
+	}
+
+	return c.recorder.Close()
+}
 
-			MaxIdleConns:        config.MaxIdleConns,
-			MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+func newTransport(config ClientConfig, proxy func(*http.Request) (*url.URL, error)) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   config.DialTimeout,
+			KeepAlive: config.DialKeepAlive,
+		}).DialContext,
 
-			IdleConnTimeout:       config.Timeout,
-			TLSHandshakeTimeout:   config.Timeout,
-			ResponseHeaderTimeout: config.Timeout,
-			ExpectContinueTimeout: config.Timeout,
-		},
+		MaxIdleConns:        config.MaxIdleConns,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerHost,
 
-		Timeout: config.Timeout,
+		IdleConnTimeout:       config.Timeout,
+		TLSHandshakeTimeout:   config.Timeout,
+		ResponseHeaderTimeout: config.Timeout,
+		ExpectContinueTimeout: config.Timeout,
 	}
 }
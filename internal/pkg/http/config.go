@@ -1,6 +1,10 @@
 package http
 
-import "time"
+import (
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/ratelimit"
+)
 
 type ServerConfig struct {
 	Port int `config:"port"`
@@ -26,4 +30,36 @@ type ClientConfig struct {
 	TLSHandshakeTimeout   time.Duration `config:"tls_handshake_timeout"`
 	ResponseHeaderTimeout time.Duration `config:"response_header_timeout"`
 	ExpectContinueTimeout time.Duration `config:"expect_continue_timeout"`
+
+	CircuitBreaker CircuitBreakerConfig `config:"circuit_breaker"`
+	RateLimit      ratelimit.Config     `config:"rate_limit"`
+
+	Proxy    ProxyConfig     `config:"proxy"`
+	Profiles []HeaderProfile `config:"profiles"`
+
+	Cassette CassetteConfig `config:"cassette"`
+
+	StatsInterval time.Duration `config:"stats_interval"`
+}
+
+type CassetteConfig struct {
+	Mode string `config:"mode"`
+	Path string `config:"path"`
+}
+
+type ProxyConfig struct {
+	URLs          []string      `config:"urls"`
+	Assignment    string        `config:"assignment"`
+	EjectDuration time.Duration `config:"eject_duration"`
+}
+
+type HeaderProfile struct {
+	Headers map[string]string `config:"headers"`
+}
+
+type CircuitBreakerConfig struct {
+	FailureThreshold uint          `config:"failure_threshold"`
+	CoolDown         time.Duration `config:"cool_down"`
+	HalfOpenRequests uint          `config:"half_open_requests"`
+	FailureStatuses  []int         `config:"failure_statuses"`
 }
@@ -0,0 +1,261 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+const (
+	ProxyAssignmentRequest = "request"
+	ProxyAssignmentSticky  = "sticky"
+
+	minProxyScore = 0.05
+)
+
+var ErrNoProxyAvailable = errors.New("no proxy available")
+
+type routeKey struct{}
+
+// WithRouteKey makes sticky assignment send all requests with the same key through the same proxy and profile.
+func WithRouteKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routeKey{}, key)
+}
+
+type ProxyStats struct {
+	URL       string  `json:"url"`
+	Score     float64 `json:"score"`
+	Ejected   bool    `json:"ejected"`
+	Requests  uint64  `json:"requests"`
+	Failures  uint64  `json:"failures"`
+	Throttled uint64  `json:"throttled"`
+	Ejections uint64  `json:"ejections"`
+}
+
+type proxy struct {
+	url       string
+	transport http.RoundTripper
+	// the direct route is the only way out without proxies, it is never ejected
+	isDirect bool
+
+	mu           sync.Mutex
+	score        float64
+	ejectedUntil time.Time
+
+	requests  atomic.Uint64
+	failures  atomic.Uint64
+	throttled atomic.Uint64
+	ejections atomic.Uint64
+}
+
+func (p *proxy) isAvailable(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return now.After(p.ejectedUntil)
+}
+
+func (p *proxy) getScore() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.score
+}
+
+type ProxyPool struct {
+	logger log.Logger
+	config ProxyConfig
+
+	proxies  []*proxy
+	profiles []HeaderProfile
+	counter  atomic.Uint64
+}
+
+func NewProxyPool(logger log.Logger, config ClientConfig) (*ProxyPool, error) {
+	pool := &ProxyPool{
+		logger:   logger,
+		config:   config.Proxy,
+		profiles: config.Profiles,
+	}
+
+	for _, rawURL := range config.Proxy.URLs {
+		proxyURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxy pool parse proxy url: %w", err)
+		}
+
+		if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5" {
+			return nil, fmt.Errorf("proxy pool unsupported proxy scheme %q", proxyURL.Scheme)
+		}
+
+		pool.proxies = append(pool.proxies, &proxy{
+			url:       proxyURL.Redacted(),
+			transport: newTransport(config, http.ProxyURL(proxyURL)),
+			score:     1,
+		})
+	}
+
+	if len(pool.proxies) == 0 {
+		pool.proxies = append(pool.proxies, &proxy{
+			url:       "direct",
+			transport: newTransport(config, http.ProxyFromEnvironment),
+			isDirect:  true,
+			score:     1,
+		})
+	}
+
+	return pool, nil
+}
+
+func (p *ProxyPool) RoundTrip(request *http.Request) (*http.Response, error) {
+	key, isSticky := request.Context().Value(routeKey{}).(string)
+	isSticky = isSticky && p.config.Assignment == ProxyAssignmentSticky
+
+	selected := p.selectProxy(key, isSticky)
+	if selected == nil {
+		return nil, ErrNoProxyAvailable
+	}
+
+	if profile, ok := p.selectProfile(key, isSticky); ok {
+		request = request.Clone(request.Context())
+		for name, value := range profile.Headers {
+			request.Header.Set(name, value)
+		}
+	}
+
+	selected.requests.Add(1)
+
+	response, err := selected.transport.RoundTrip(request)
+	switch {
+	case request.Context().Err() != nil:
+	case err != nil:
+		selected.failures.Add(1)
+		p.penalize(selected, false)
+	case response.StatusCode == http.StatusTooManyRequests:
+		selected.throttled.Add(1)
+		p.penalize(selected, true)
+	case response.StatusCode >= http.StatusInternalServerError:
+		selected.failures.Add(1)
+		p.penalize(selected, false)
+	default:
+		selected.mu.Lock()
+		selected.score = min(selected.score+0.1, 1)
+		selected.mu.Unlock()
+	}
+
+	return response, err
+}
+
+func (p *ProxyPool) Stats() []ProxyStats {
+	now := time.Now()
+	stats := make([]ProxyStats, 0, len(p.proxies))
+
+	for _, item := range p.proxies {
+		stats = append(stats, ProxyStats{
+			URL:       item.url,
+			Score:     item.getScore(),
+			Ejected:   !item.isAvailable(now),
+			Requests:  item.requests.Load(),
+			Failures:  item.failures.Load(),
+			Throttled: item.throttled.Load(),
+			Ejections: item.ejections.Load(),
+		})
+	}
+
+	return stats
+}
+
+func (p *ProxyPool) selectProxy(key string, isSticky bool) *proxy {
+	now := time.Now()
+
+	var available []*proxy
+	for _, item := range p.proxies {
+		if item.isAvailable(now) {
+			available = append(available, item)
+		}
+	}
+
+	if len(available) == 0 {
+		return nil
+	}
+
+	if isSticky {
+		// rendezvous hashing keeps the key on the same proxy while it stays available
+		var selected *proxy
+		var best uint64
+		for _, item := range available {
+			if weight := hashKey(key, item.url); selected == nil || weight > best {
+				selected, best = item, weight
+			}
+		}
+		return selected
+	}
+
+	var total float64
+	for _, item := range available {
+		total += max(item.getScore(), minProxyScore)
+	}
+
+	point := rand.Float64() * total
+	for _, item := range available {
+		point -= max(item.getScore(), minProxyScore)
+		if point <= 0 {
+			return item
+		}
+	}
+
+	return available[len(available)-1]
+}
+
+func (p *ProxyPool) selectProfile(key string, isSticky bool) (HeaderProfile, bool) {
+	if len(p.profiles) == 0 {
+		return HeaderProfile{}, false
+	}
+
+	if isSticky {
+		return p.profiles[hashKey(key, "")%uint64(len(p.profiles))], true
+	}
+
+	return p.profiles[p.counter.Add(1)%uint64(len(p.profiles))], true
+}
+
+func (p *ProxyPool) penalize(item *proxy, isThrottled bool) {
+	item.mu.Lock()
+	item.score = max(item.score/2, minProxyScore)
+	isEjected := !item.isDirect && (isThrottled || item.score <= minProxyScore)
+	if isEjected {
+		item.ejectedUntil = time.Now().Add(p.config.EjectDuration)
+	}
+	score := item.score
+	item.mu.Unlock()
+
+	if !isEjected {
+		return
+	}
+
+	item.ejections.Add(1)
+
+	p.logger.Warn().
+		Str("proxy", item.url).
+		Float64("score", score).
+		Uint64("requests", item.requests.Load()).
+		Uint64("failures", item.failures.Load()).
+		Uint64("throttled", item.throttled.Load()).
+		Uint64("ejections", item.ejections.Load()).
+		Str("duration", p.config.EjectDuration.String()).
+		Msg("proxy ejected")
+}
+
+func hashKey(key string, salt string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(key))
+	_, _ = hash.Write([]byte(salt))
+	return hash.Sum64()
+}
@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+// testProxy answers requests sent through it with the configured status, an http proxy receives the absolute target url.
+type testProxy struct {
+	server   *httptest.Server
+	status   atomic.Int64
+	requests atomic.Int64
+	profile  atomic.Value
+}
+
+func newTestProxy(t *testing.T) *testProxy {
+	t.Helper()
+
+	p := &testProxy{}
+	p.status.Store(http.StatusOK)
+	p.profile.Store("")
+	p.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.requests.Add(1)
+		p.profile.Store(r.Header.Get("X-Profile"))
+		w.WriteHeader(int(p.status.Load()))
+	}))
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func newTestProxyPool(t *testing.T, assignment string, profiles []HeaderProfile, proxies ...*testProxy) *ProxyPool {
+	t.Helper()
+
+	config := ClientConfig{
+		Timeout:  5 * time.Second,
+		Profiles: profiles,
+		Proxy: ProxyConfig{
+			Assignment:    assignment,
+			EjectDuration: time.Hour,
+		},
+	}
+	for _, item := range proxies {
+		config.Proxy.URLs = append(config.Proxy.URLs, item.server.URL)
+	}
+
+	pool, err := NewProxyPool(log.NewNop(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pool
+}
+
+func poolGet(pool *ProxyPool, ctx context.Context, target string) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := pool.RoundTrip(request)
+	if err != nil {
+		return 0, err
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+	_ = response.Body.Close()
+
+	return response.StatusCode, nil
+}
+
+func TestProxyPoolEjectsThrottledProxy(t *testing.T) {
+	first, second := newTestProxy(t), newTestProxy(t)
+	first.status.Store(http.StatusTooManyRequests)
+	pool := newTestProxyPool(t, ProxyAssignmentRequest, nil, first, second)
+
+	for first.requests.Load() == 0 {
+		if _, err := poolGet(pool, context.Background(), "http://catalog.test/page"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	before := first.requests.Load()
+	for i := 0; i < 10; i++ {
+		status, err := poolGet(pool, context.Background(), "http://catalog.test/page")
+		if err != nil || status != http.StatusOK {
+			t.Fatalf("expected requests served by the second proxy, got %d, %v", status, err)
+		}
+	}
+
+	if first.requests.Load() != before {
+		t.Errorf("ejected proxy must not get requests, got %d more", first.requests.Load()-before)
+	}
+
+	stats := pool.Stats()
+	if !stats[0].Ejected || stats[0].Throttled != 1 || stats[0].Ejections != 1 || stats[1].Ejected {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	second.status.Store(http.StatusTooManyRequests)
+	_, _ = poolGet(pool, context.Background(), "http://catalog.test/page")
+	if _, err := poolGet(pool, context.Background(), "http://catalog.test/page"); !errors.Is(err, ErrNoProxyAvailable) {
+		t.Errorf("expected no proxy available, got %v", err)
+	}
+}
+
+func TestProxyPoolStickyAssignment(t *testing.T) {
+	first, second := newTestProxy(t), newTestProxy(t)
+	profiles := []HeaderProfile{
+		{Headers: map[string]string{"X-Profile": "a"}},
+		{Headers: map[string]string{"X-Profile": "b"}},
+	}
+	pool := newTestProxyPool(t, ProxyAssignmentSticky, profiles, first, second)
+
+	ctx := WithRouteKey(context.Background(), "dresses")
+	for i := 0; i < 10; i++ {
+		if _, err := poolGet(pool, ctx, "http://catalog.test/page"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	sticky, other := first, second
+	if first.requests.Load() == 0 {
+		sticky, other = second, first
+	}
+
+	if sticky.requests.Load() != 10 || other.requests.Load() != 0 {
+		t.Errorf("expected all requests of the key through one proxy, got %d and %d", first.requests.Load(), second.requests.Load())
+	}
+
+	profile := sticky.profile.Load().(string)
+	if profile != "a" && profile != "b" {
+		t.Errorf("expected a profile header, got %q", profile)
+	}
+
+	// the key moves to the other proxy while its proxy is ejected
+	sticky.status.Store(http.StatusTooManyRequests)
+	_, _ = poolGet(pool, ctx, "http://catalog.test/page")
+	if _, err := poolGet(pool, ctx, "http://catalog.test/page"); err != nil || other.requests.Load() != 1 {
+		t.Errorf("expected the key moved to the other proxy, got %d requests, error %v", other.requests.Load(), err)
+	}
+}
+
+func TestProxyPoolNeverEjectsDirect(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusTooManyRequests)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	pool := newTestProxyPool(t, ProxyAssignmentRequest, []HeaderProfile{{Headers: map[string]string{"X-Profile": "a"}}})
+
+	for i := 0; i < 10; i++ {
+		got, err := poolGet(pool, context.Background(), server.URL)
+		if err != nil || got != http.StatusTooManyRequests {
+			t.Fatalf("expected the direct route to keep serving, got %d, %v", got, err)
+		}
+	}
+
+	status.Store(http.StatusOK)
+	if got, err := poolGet(pool, context.Background(), server.URL); err != nil || got != http.StatusOK {
+		t.Fatalf("expected the direct route to recover, got %d, %v", got, err)
+	}
+
+	stats := pool.Stats()
+	if len(stats) != 1 || stats[0].URL != "direct" || stats[0].Ejected || stats[0].Ejections != 0 || stats[0].Throttled != 10 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
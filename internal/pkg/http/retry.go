@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type RetryConfig struct {
+	Count    uint
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Statuses []int
+}
+
+type RetryPolicy struct {
+	logger log.Logger
+	config RetryConfig
+}
+
+func NewRetryPolicy(logger log.Logger, config RetryConfig) *RetryPolicy {
+	return &RetryPolicy{
+		logger: logger,
+		config: config,
+	}
+}
+
+// Do sends the request until it succeeds, fails with a permanent error or retries are exhausted.
+// The response with a retryable status is returned as is when there are no retries left
+// or the server asks to wait longer than MaxDelay.
+func (p *RetryPolicy) Do(client *http.Client, request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	for attempt := uint(0); ; attempt++ {
+		if attempt > 0 && request.GetBody != nil {
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("retry policy get request body: %w", err)
+			}
+			request.Body = body
+		}
+
+		response, err := client.Do(request)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= p.config.Count || !IsRetryableError(err) {
+				return nil, err
+			}
+
+			delay := p.backoff(attempt)
+			p.logger.Warn().Err(err).
+				Str("url", request.URL.Redacted()).
+				Uint("try", attempt+1).
+				Str("delay", delay.String()).
+				Msg("http request failed trying to retry")
+
+			if err = sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if attempt >= p.config.Count || !slices.Contains(p.config.Statuses, response.StatusCode) {
+			return response, nil
+		}
+
+		delay, ok := retryAfter(response.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			delay = p.backoff(attempt)
+		} else if p.config.MaxDelay > 0 && delay > p.config.MaxDelay {
+			return response, nil
+		}
+
+		p.logger.Warn().
+			Str("url", request.URL.Redacted()).
+			Int("status", response.StatusCode).
+			Uint("try", attempt+1).
+			Str("delay", delay.String()).
+			Msg("http response bad status trying to retry")
+
+		drainBody(p.logger, response)
+
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt uint) time.Duration {
+	if p.config.Delay <= 0 {
+		return 0
+	}
+
+	ceiling := p.config.Delay << min(attempt, 30)
+	if ceiling <= 0 || (p.config.MaxDelay > 0 && ceiling > p.config.MaxDelay) {
+		ceiling = p.config.MaxDelay
+	}
+
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+func IsRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func drainBody(logger log.Logger, response *http.Response) {
+	if _, err := io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16)); err != nil {
+		logger.Warn().Err(err).Msg("failed to drain response body")
+	}
+
+	if err := response.Body.Close(); err != nil {
+		logger.Warn().Err(err).Msg("failed to close response body")
+	}
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type fakeResult struct {
+	status     int
+	retryAfter string
+	err        error
+}
+
+// fakeRoundTripper answers with the results in order and repeats the last one.
+type fakeRoundTripper struct {
+	results  []fakeResult
+	attempts int
+}
+
+func (f *fakeRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	result := f.results[min(f.attempts, len(f.results)-1)]
+	f.attempts++
+
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	header := make(http.Header)
+	if result.retryAfter != "" {
+		header.Set("Retry-After", result.retryAfter)
+	}
+
+	return &http.Response{StatusCode: result.status, Header: header, Body: http.NoBody, Request: request}, nil
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	statuses := []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+	tests := []struct {
+		name     string
+		count    uint
+		results  []fakeResult
+		status   int
+		err      error
+		attempts int
+	}{
+		{name: "success", count: 3, results: []fakeResult{{status: http.StatusOK}}, status: http.StatusOK, attempts: 1},
+		{
+			name:     "retryable status",
+			count:    3,
+			results:  []fakeResult{{status: http.StatusServiceUnavailable}, {status: http.StatusOK}},
+			status:   http.StatusOK,
+			attempts: 2,
+		},
+		{name: "status not in list", count: 3, results: []fakeResult{{status: http.StatusBadGateway}}, status: http.StatusBadGateway, attempts: 1},
+		{name: "client error", count: 3, results: []fakeResult{{status: http.StatusNotFound}}, status: http.StatusNotFound, attempts: 1},
+		{
+			name:     "retries exhausted",
+			count:    2,
+			results:  []fakeResult{{status: http.StatusServiceUnavailable}},
+			status:   http.StatusServiceUnavailable,
+			attempts: 3,
+		},
+		{name: "no retries", results: []fakeResult{{status: http.StatusTooManyRequests}}, status: http.StatusTooManyRequests, attempts: 1},
+		{
+			name:     "retry after within max delay",
+			count:    3,
+			results:  []fakeResult{{status: http.StatusTooManyRequests, retryAfter: "0"}, {status: http.StatusOK}},
+			status:   http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name:     "retry after above max delay",
+			count:    3,
+			results:  []fakeResult{{status: http.StatusTooManyRequests, retryAfter: "3600"}},
+			status:   http.StatusTooManyRequests,
+			attempts: 1,
+		},
+		{
+			name:     "retryable error",
+			count:    3,
+			results:  []fakeResult{{err: syscall.ECONNRESET}, {status: http.StatusOK}},
+			status:   http.StatusOK,
+			attempts: 2,
+		},
+		{
+			name:     "retryable error exhausted",
+			count:    1,
+			results:  []fakeResult{{err: syscall.ECONNRESET}},
+			err:      syscall.ECONNRESET,
+			attempts: 2,
+		},
+		{
+			name:     "permanent error",
+			count:    3,
+			results:  []fakeResult{{err: context.Canceled}},
+			err:      context.Canceled,
+			attempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &fakeRoundTripper{results: tt.results}
+			policy := NewRetryPolicy(log.NewNop(), RetryConfig{
+				Count:    tt.count,
+				Delay:    time.Millisecond,
+				MaxDelay: time.Second,
+				Statuses: statuses,
+			})
+
+			request, err := http.NewRequest(http.MethodGet, "http://catalog.test/page", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			response, err := policy.Do(&http.Client{Transport: transport}, request)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("expected error %v, got %v", tt.err, err)
+				}
+			} else if err != nil || response.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %v, %v", tt.status, response, err)
+			}
+
+			if transport.attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, transport.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		delay    time.Duration
+		maxDelay time.Duration
+		attempt  uint
+		ceiling  time.Duration
+	}{
+		{name: "no delay", attempt: 3},
+		{name: "first attempt", delay: 10 * time.Millisecond, maxDelay: time.Second, ceiling: 10 * time.Millisecond},
+		{name: "doubles per attempt", delay: 10 * time.Millisecond, maxDelay: time.Second, attempt: 3, ceiling: 80 * time.Millisecond},
+		{name: "capped at max delay", delay: 10 * time.Millisecond, maxDelay: 50 * time.Millisecond, attempt: 3, ceiling: 50 * time.Millisecond},
+		{name: "shift is bounded", delay: time.Nanosecond, attempt: 64, ceiling: time.Nanosecond << 30},
+		{name: "overflow falls back to max delay", delay: time.Hour, maxDelay: time.Minute, attempt: 30, ceiling: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := NewRetryPolicy(log.NewNop(), RetryConfig{Delay: tt.delay, MaxDelay: tt.maxDelay})
+
+			for i := 0; i < 1000; i++ {
+				if delay := policy.backoff(tt.attempt); delay < 0 || delay > tt.ceiling {
+					t.Fatalf("expected delay within [0, %s], got %s", tt.ceiling, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{name: "missing"},
+		{name: "seconds", value: "3", delay: 3 * time.Second, ok: true},
+		{name: "zero seconds", value: "0", ok: true},
+		{name: "negative seconds", value: "-1"},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), delay: 90 * time.Second, ok: true},
+		{name: "http date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat), ok: true},
+		{name: "garbage", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := retryAfter(tt.value, now)
+			if delay != tt.delay || ok != tt.ok {
+				t.Errorf("expected %s, %t, got %s, %t", tt.delay, tt.ok, delay, ok)
+			}
+		})
+	}
+}
@@ -47,3 +47,8 @@ func New(level string, serviceName string) Logger {
 
 	return &logger
 }
+
+func NewNop() Logger {
+	logger := zerolog.Nop()
+	return &logger
+}
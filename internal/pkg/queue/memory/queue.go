@@ -72,9 +72,18 @@ func NewQueue[TMessage any](ctx context.Context, logger log.Logger, config Confi
 	return queue
 }
 
-func (q *Queue[TMessage]) Push(_ context.Context, message TMessage) error {
-	q.messages <- message
-	return nil
+// Push never blocks the producer, the message is dropped with queue.ErrQueueFull when the buffer is full.
+func (q *Queue[TMessage]) Push(ctx context.Context, message TMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case q.messages <- message:
+		return nil
+	default:
+		return queue.ErrQueueFull
+	}
 }
 
 func (q *Queue[TMessage]) Close() error {
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/queue"
+)
+
+func TestQueuePushDoesNotBlock(t *testing.T) {
+	// without a consumer goroutine the buffer is only freed by the test
+	q := &Queue[int]{messages: make(chan int, 1)}
+
+	if err := q.Push(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := q.Push(context.Background(), 2); !errors.Is(err, queue.ErrQueueFull) {
+		t.Fatalf("expected full queue, got %v", err)
+	}
+
+	<-q.messages
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := q.Push(ctx, 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled push, got %v", err)
+	}
+
+	if len(q.messages) != 0 {
+		t.Errorf("expected canceled message dropped, got %d buffered", len(q.messages))
+	}
+}
+
+func TestQueueHandlesPushedMessages(t *testing.T) {
+	handled := make(chan []int, 1)
+	q := NewQueue[int](context.Background(), log.NewNop(), Config{BatchSize: 2, BufferSize: 4, FlushInterval: time.Hour},
+		func(_ context.Context, messages []int) error {
+			handled <- append([]int(nil), messages...)
+			return nil
+		})
+
+	for _, message := range []int{1, 2} {
+		if err := q.Push(context.Background(), message); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	select {
+	case batch := <-handled:
+		if len(batch) != 2 || batch[0] != 1 || batch[1] != 2 {
+			t.Errorf("unexpected batch: %v", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not handled")
+	}
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
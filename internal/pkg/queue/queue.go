@@ -1,6 +1,12 @@
 package queue
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrQueueFull is returned by a push that would block, the message is dropped.
+var ErrQueueFull = errors.New("queue is full")
 
 type Handler[TMessage any] func(ctx context.Context, messages []TMessage) error
 
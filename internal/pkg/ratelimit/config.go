@@ -0,0 +1,11 @@
+package ratelimit
+
+type Config struct {
+	RequestsPerSecond float64 `config:"requests_per_second"`
+	Burst             int     `config:"burst"`
+	// Hosts share the global limit, subdomains included. Other hosts are only limited per host, an empty list limits all hosts.
+	Hosts []string `config:"hosts"`
+
+	PerHostRequestsPerSecond float64 `config:"per_host_requests_per_second"`
+	PerHostBurst             int     `config:"per_host_burst"`
+}
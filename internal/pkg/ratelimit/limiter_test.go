@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	limiter := NewLimiter(10, 2)
+
+	for i := 0; i < 2; i++ {
+		if delay := limiter.reserve(); delay != 0 {
+			t.Fatalf("expected burst token %d without delay, got %s", i, delay)
+		}
+	}
+
+	// tokens refill at 10 per second, the next ones are 100ms apart
+	for i, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond} {
+		if delay := limiter.reserve(); delay < expected-5*time.Millisecond || delay > expected {
+			t.Errorf("token %d: expected delay close to %s, got %s", i, expected, delay)
+		}
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	limiter := NewLimiter(1, 1)
+	limiter.reserve()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	// the canceled wait gave its token back, the next one is due in about a second and not two
+	if delay := limiter.reserve(); delay > time.Second {
+		t.Errorf("expected the canceled token returned, got delay %s", delay)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter := NewLimiter(100, 1)
+
+	if delay, err := limiter.Wait(context.Background()); err != nil || delay != 0 {
+		t.Fatalf("expected first token immediately, got %s, %v", delay, err)
+	}
+
+	start := time.Now()
+	delay, err := limiter.Wait(context.Background())
+	if err != nil || delay <= 0 {
+		t.Fatalf("expected second token delayed, got %s, %v", delay, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("expected to wait %s, waited %s", delay, elapsed)
+	}
+}
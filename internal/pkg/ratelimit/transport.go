@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type Stats struct {
+	Requests     uint64        `json:"requests"`
+	Delayed      uint64        `json:"delayed"`
+	TotalWait    time.Duration `json:"total_wait"`
+	MaxWait      time.Duration `json:"max_wait"`
+	AverageDelay time.Duration `json:"average_delay"`
+}
+
+type Transport struct {
+	logger log.Logger
+	config Config
+	next   http.RoundTripper
+
+	global *Limiter
+
+	mu    sync.Mutex
+	hosts map[string]*Limiter
+
+	requests  atomic.Uint64
+	delayed   atomic.Uint64
+	totalWait atomic.Int64
+	maxWait   atomic.Int64
+}
+
+func NewTransport(logger log.Logger, config Config, next http.RoundTripper) *Transport {
+	transport := &Transport{
+		logger: logger,
+		config: config,
+		next:   next,
+		hosts:  make(map[string]*Limiter),
+	}
+
+	if config.RequestsPerSecond > 0 {
+		transport.global = NewLimiter(config.RequestsPerSecond, config.Burst)
+	}
+
+	return transport
+}
+
+func (t *Transport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var wait time.Duration
+
+	if t.global != nil && t.isGlobalHost(request.URL.Hostname()) {
+		delay, err := t.global.Wait(request.Context())
+		if err != nil {
+			return nil, err
+		}
+		wait += delay
+	}
+
+	if limiter := t.hostLimiter(request.URL.Host); limiter != nil {
+		delay, err := limiter.Wait(request.Context())
+		if err != nil {
+			return nil, err
+		}
+		wait += delay
+	}
+
+	t.observe(request.URL.Host, wait)
+
+	return t.next.RoundTrip(request)
+}
+
+func (t *Transport) Stats() Stats {
+	stats := Stats{
+		Requests:  t.requests.Load(),
+		Delayed:   t.delayed.Load(),
+		TotalWait: time.Duration(t.totalWait.Load()),
+		MaxWait:   time.Duration(t.maxWait.Load()),
+	}
+
+	if stats.Delayed > 0 {
+		stats.AverageDelay = stats.TotalWait / time.Duration(stats.Delayed)
+	}
+
+	return stats
+}
+
+func (t *Transport) isGlobalHost(host string) bool {
+	if len(t.config.Hosts) == 0 {
+		return true
+	}
+
+	for _, item := range t.config.Hosts {
+		if host == item || strings.HasSuffix(host, "."+item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *Transport) hostLimiter(host string) *Limiter {
+	if t.config.PerHostRequestsPerSecond <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limiter, ok := t.hosts[host]
+	if !ok {
+		limiter = NewLimiter(t.config.PerHostRequestsPerSecond, t.config.PerHostBurst)
+		t.hosts[host] = limiter
+	}
+
+	return limiter
+}
+
+func (t *Transport) observe(host string, wait time.Duration) {
+	t.requests.Add(1)
+	if wait <= 0 {
+		return
+	}
+
+	t.delayed.Add(1)
+	t.totalWait.Add(int64(wait))
+
+	for {
+		current := t.maxWait.Load()
+		if int64(wait) <= current || t.maxWait.CompareAndSwap(current, int64(wait)) {
+			break
+		}
+	}
+
+	t.logger.Debug().
+		Str("host", host).
+		Str("wait", wait.String()).
+		Msg("request delayed by rate limiter")
+}
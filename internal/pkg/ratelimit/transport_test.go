@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/iamsorryprincess/wildberries-bot/internal/pkg/log"
+)
+
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func newTestTransport(config Config) *Transport {
+	return NewTransport(log.NewNop(), config, roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: request}, nil
+	}))
+}
+
+func get(t *testing.T, transport *Transport, target string) {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = transport.RoundTrip(request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTransportGlobalHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   []string
+		targets []string
+		delayed uint64
+	}{
+		{
+			name:    "all hosts without a list",
+			targets: []string{"https://catalog.wb.ru/a", "https://basket-01.wbbasket.ru/b", "https://www.ozon.ru/c"},
+			delayed: 2,
+		},
+		{
+			name:    "listed hosts share the limit",
+			hosts:   []string{"catalog.wb.ru", "search.wb.ru"},
+			targets: []string{"https://catalog.wb.ru/a", "https://search.wb.ru/b", "https://catalog.wb.ru/c"},
+			delayed: 2,
+		},
+		{
+			name:    "other hosts are not limited",
+			hosts:   []string{"catalog.wb.ru"},
+			targets: []string{"https://catalog.wb.ru/a", "https://basket-01.wbbasket.ru/b", "https://www.ozon.ru/c", "https://static-basket-01.wbbasket.ru/d"},
+		},
+		{
+			name:    "subdomains of listed hosts",
+			hosts:   []string{"wb.ru"},
+			targets: []string{"https://catalog.wb.ru/a", "https://search.wb.ru/b"},
+			delayed: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// one token per 10ms keeps delays measurable and the test fast
+			transport := newTestTransport(Config{RequestsPerSecond: 100, Burst: 1, Hosts: tt.hosts})
+
+			for _, target := range tt.targets {
+				get(t, transport, target)
+			}
+
+			stats := transport.Stats()
+			if stats.Requests != uint64(len(tt.targets)) || stats.Delayed != tt.delayed {
+				t.Errorf("expected %d requests and %d delayed, got %+v", len(tt.targets), tt.delayed, stats)
+			}
+
+			if tt.delayed > 0 && (stats.MaxWait <= 0 || stats.AverageDelay <= 0 || stats.TotalWait < stats.MaxWait) {
+				t.Errorf("unexpected wait stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestTransportPerHost(t *testing.T) {
+	transport := newTestTransport(Config{PerHostRequestsPerSecond: 100, PerHostBurst: 1})
+
+	for _, target := range []string{"https://catalog.wb.ru/a", "https://www.ozon.ru/b", "https://catalog.wb.ru/c"} {
+		get(t, transport, target)
+	}
+
+	if stats := transport.Stats(); stats.Requests != 3 || stats.Delayed != 1 {
+		t.Errorf("expected only the second catalog request delayed, got %+v", stats)
+	}
+}
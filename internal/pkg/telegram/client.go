@@ -2,11 +2,16 @@ package telegram
 
 import (
 	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/go-telegram/bot"
 )
 
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
 type BotClient struct {
 	config Config
 
@@ -29,12 +34,59 @@ func NewBotClient(config Config, options ...bot.Option) (*BotClient, error) {
 	return botClient, nil
 }
 
-func (c *BotClient) Start(ctx context.Context) {
+func (c *BotClient) Start(ctx context.Context) error {
+	if c.config.IsWebhook() {
+		if _, err := c.SetWebhook(ctx, &bot.SetWebhookParams{
+			URL:         c.config.WebhookURL,
+			SecretToken: c.config.WebhookSecret,
+		}); err != nil {
+			return fmt.Errorf("telegram set webhook: %w", err)
+		}
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.Bot.StartWebhook(ctx)
+		}()
+
+		return nil
+	}
+
+	// polling doesn't work while a webhook from a previous run is still registered
+	if _, err := c.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		return fmt.Errorf("telegram delete webhook: %w", err)
+	}
+
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
 		c.Bot.Start(ctx)
 	}()
+
+	return nil
+}
+
+// WebhookHandler is the only place the secret token is checked, the bot is built without its own secret check
+// as it answers a wrong secret with 200.
+func (c *BotClient) WebhookHandler() http.Handler {
+	handler := c.Bot.WebhookHandler()
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if c.config.WebhookSecret != "" {
+			token := request.Header.Get(secretTokenHeader)
+			if subtle.ConstantTimeCompare([]byte(token), []byte(c.config.WebhookSecret)) != 1 {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		handler(writer, request)
+	})
 }
 
 func (c *BotClient) Close() error {
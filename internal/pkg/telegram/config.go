@@ -1,5 +1,22 @@
 package telegram
 
+import "net/url"
+
 type Config struct {
 	Token string `config:"token"`
+
+	WebhookURL    string `config:"webhook_url"`
+	WebhookSecret string `config:"webhook_secret"`
+}
+
+func (c Config) IsWebhook() bool {
+	return c.WebhookURL != ""
+}
+
+func (c Config) WebhookPath() string {
+	webhookURL, err := url.Parse(c.WebhookURL)
+	if err != nil || webhookURL.Path == "" {
+		return "/"
+	}
+	return webhookURL.Path
 }
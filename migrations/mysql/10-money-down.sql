@@ -0,0 +1,35 @@
+alter table tracking_logs modify column price bigint unsigned not null;
+update tracking_logs set price = price div 100;
+
+alter table tracking_settings modify column max_price decimal(14, 2) not null default 0.00;
+update tracking_settings set max_price = max_price / 100;
+alter table tracking_settings modify column max_price decimal(10, 2) not null default 0.00;
+
+alter table products_sizes
+  modify column first_price decimal(14, 2) not null default 0.00,
+  modify column previous_price decimal(14, 2) not null default 0.00,
+  modify column current_price decimal(14, 2) not null default 0.00,
+  modify column basic_price decimal(14, 2) not null default 0.00,
+  modify column product_price decimal(14, 2) not null default 0.00,
+  modify column previous_product_price decimal(14, 2) not null default 0.00,
+  modify column logistics_price decimal(14, 2) not null default 0.00,
+  add column current_price_int bigint unsigned not null default 0 after current_price;
+
+update products_sizes set
+  first_price = first_price / 100,
+  previous_price = previous_price / 100,
+  current_price = current_price / 100,
+  basic_price = basic_price / 100,
+  product_price = product_price / 100,
+  previous_product_price = previous_product_price / 100,
+  logistics_price = logistics_price / 100,
+  current_price_int = floor(current_price);
+
+alter table products_sizes
+  modify column first_price decimal(10, 2) not null default 0.00,
+  modify column previous_price decimal(10, 2) not null default 0.00,
+  modify column current_price decimal(10, 2) not null default 0.00,
+  modify column basic_price decimal(10, 2) not null default 0.00,
+  modify column product_price decimal(10, 2) not null default 0.00,
+  modify column previous_product_price decimal(10, 2) not null default 0.00,
+  modify column logistics_price decimal(10, 2) not null default 0.00;
\ No newline at end of file
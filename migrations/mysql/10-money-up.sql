@@ -0,0 +1,41 @@
+ALTER TABLE products_sizes
+  MODIFY COLUMN `first_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `previous_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `current_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `basic_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `product_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `previous_product_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+  MODIFY COLUMN `logistics_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE products_sizes SET
+  first_price = first_price * 100,
+  previous_price = previous_price * 100,
+  current_price = current_price * 100,
+  basic_price = basic_price * 100,
+  product_price = product_price * 100,
+  previous_product_price = previous_product_price * 100,
+  logistics_price = logistics_price * 100;
+
+ALTER TABLE products_sizes
+  MODIFY COLUMN `first_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `previous_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `current_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `basic_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `product_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `previous_product_price` BIGINT NOT NULL DEFAULT 0,
+  MODIFY COLUMN `logistics_price` BIGINT NOT NULL DEFAULT 0,
+  DROP COLUMN `current_price_int`;
+
+ALTER TABLE tracking_settings MODIFY COLUMN `max_price` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
+UPDATE tracking_settings SET max_price = max_price * 100;
+ALTER TABLE tracking_settings MODIFY COLUMN `max_price` BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE tracking_logs MODIFY COLUMN `price` BIGINT NOT NULL;
+UPDATE tracking_logs SET price = price * 100;
+
+-- logged prices were whole rubles, a price with kopecks would never equal them and re-fire once,
+-- so logs matching the truncated current price take the exact one
+UPDATE tracking_logs AS tl
+  JOIN products_sizes AS ps ON ps.product_id = tl.product_id AND ps.size_id = tl.size_id
+SET tl.price = IF(tl.price = ps.current_price DIV 100 * 100, ps.current_price, ps.product_price)
+WHERE tl.price IN (ps.current_price DIV 100 * 100, ps.product_price DIV 100 * 100);
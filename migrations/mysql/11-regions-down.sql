@@ -0,0 +1,16 @@
+alter table products_sizes_stock_history
+  drop index index_product_size_region_created_at,
+  add index index_product_size_created_at (product_id, size_id, created_at);
+delete from products_sizes_stock_history where region_id <> 1;
+alter table products_sizes_stock_history drop column region_id;
+
+delete from products_sizes where region_id <> 1;
+alter table products_sizes
+  drop foreign key fk_products_sizes_region,
+  drop index uk_product_size_region,
+  add unique key uk_product_size (product_id, size_id),
+  drop column region_id;
+
+alter table chats drop foreign key fk_chats_region, drop column region_id;
+
+drop table regions;
\ No newline at end of file
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS regions (
+  `id` BIGINT UNSIGNED AUTO_INCREMENT NOT NULL PRIMARY KEY,
+  `name` VARCHAR(100) NOT NULL,
+  `dest` BIGINT SIGNED NOT NULL,
+  `is_default` TINYINT(1) NOT NULL DEFAULT 0,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  `updated_at` DATETIME NULL,
+  UNIQUE KEY `uk_regions_dest` (`dest`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
+
+INSERT INTO regions (id, name, dest, is_default) VALUES (1, 'Москва', -1257786, 1);
+
+ALTER TABLE chats
+  ADD COLUMN `region_id` BIGINT UNSIGNED NOT NULL DEFAULT 1 AFTER `chat_id`,
+  ADD CONSTRAINT `fk_chats_region` FOREIGN KEY (region_id) REFERENCES regions(id);
+
+ALTER TABLE products_sizes
+  ADD COLUMN `region_id` BIGINT UNSIGNED NOT NULL DEFAULT 1 AFTER `size_id`,
+  ADD CONSTRAINT `fk_products_sizes_region` FOREIGN KEY (region_id) REFERENCES regions(id) ON DELETE CASCADE,
+  DROP INDEX `uk_product_size`,
+  ADD UNIQUE KEY `uk_product_size_region` (`product_id`, `size_id`, `region_id`);
+
+ALTER TABLE products_sizes_stock_history
+  ADD COLUMN `region_id` BIGINT UNSIGNED NOT NULL DEFAULT 1 AFTER `size_id`,
+  DROP INDEX `index_product_size_created_at`,
+  ADD INDEX `index_product_size_region_created_at` (product_id, size_id, region_id, created_at);
@@ -0,0 +1,3 @@
+drop table categories_products;
+delete from categories where type <> 0;
+alter table categories drop column type, drop column query;
\ No newline at end of file
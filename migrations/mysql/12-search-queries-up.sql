@@ -0,0 +1,15 @@
+ALTER TABLE categories
+  ADD COLUMN `type` TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER `id`,
+  ADD COLUMN `query` VARCHAR(200) NULL AFTER `product_url`;
+
+CREATE TABLE IF NOT EXISTS categories_products (
+  `category_id` BIGINT UNSIGNED NOT NULL,
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (`category_id`, `product_id`),
+  INDEX `index_product_id` (product_id),
+  FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
+
+INSERT IGNORE INTO categories_products (category_id, product_id) SELECT category_id, id FROM products;
@@ -0,0 +1,2 @@
+alter table product_details drop index index_seller_name, drop index index_seller_id;
+alter table products drop index index_brand, drop index index_brand_id;
\ No newline at end of file
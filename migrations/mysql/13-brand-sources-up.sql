@@ -0,0 +1,7 @@
+ALTER TABLE products
+  ADD INDEX `index_brand_id` (`brand_id`),
+  ADD INDEX `index_brand` (`brand`);
+
+ALTER TABLE product_details
+  ADD INDEX `index_seller_id` (`seller_id`),
+  ADD INDEX `index_seller_name` (`seller_name`);
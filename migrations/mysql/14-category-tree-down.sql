@@ -0,0 +1 @@
+drop table category_tree;
\ No newline at end of file
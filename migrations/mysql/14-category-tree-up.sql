@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS category_tree (
+  `id` BIGINT UNSIGNED NOT NULL,
+  `parent_id` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  `name` VARCHAR(255) NOT NULL,
+  `url` VARCHAR(512) NOT NULL DEFAULT '',
+  `shard` VARCHAR(100) NOT NULL DEFAULT '',
+  `catalog_id` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  `position` INT UNSIGNED NOT NULL DEFAULT 0,
+  `category_id` BIGINT UNSIGNED NULL,
+  `synced_at` DATETIME NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (`id`),
+  INDEX `index_parent_id_position` (`parent_id`, `position`),
+  UNIQUE KEY `uk_category_id` (`category_id`),
+  CONSTRAINT `fk_category_tree_category` FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
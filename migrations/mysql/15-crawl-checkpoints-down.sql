@@ -0,0 +1 @@
+alter table categories drop column crawl_region_id, drop column crawl_page, drop column crawl_passes;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE categories
+  ADD COLUMN `crawl_region_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER `throttle_count`,
+  ADD COLUMN `crawl_page` INT UNSIGNED NOT NULL DEFAULT 1 AFTER `crawl_region_id`,
+  ADD COLUMN `crawl_passes` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `crawl_page`;
+
+UPDATE categories SET crawl_passes = 1 WHERE crawled_at IS NOT NULL;
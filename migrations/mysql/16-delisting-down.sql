@@ -0,0 +1,3 @@
+alter table categories drop column crawl_pass_started_at;
+alter table products_sizes drop column last_seen_at, drop column missed_passes, drop column is_delisted;
+alter table products drop index index_delisted_at, drop column last_seen_at, drop column missed_passes, drop column delisted_at;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE products
+  ADD COLUMN `last_seen_at` DATETIME NULL,
+  ADD COLUMN `missed_passes` INT UNSIGNED NOT NULL DEFAULT 0,
+  ADD COLUMN `delisted_at` DATETIME NULL,
+  ADD INDEX `index_delisted_at` (`delisted_at`);
+
+ALTER TABLE products_sizes
+  ADD COLUMN `last_seen_at` DATETIME NULL,
+  ADD COLUMN `missed_passes` INT UNSIGNED NOT NULL DEFAULT 0,
+  ADD COLUMN `is_delisted` TINYINT(1) NOT NULL DEFAULT 0;
+
+ALTER TABLE categories ADD COLUMN `crawl_pass_started_at` DATETIME NULL AFTER `crawl_passes`;
+
+UPDATE products SET last_seen_at = COALESCE(updated_at, created_at);
+UPDATE products_sizes SET last_seen_at = COALESCE(updated_at, created_at);
@@ -0,0 +1,2 @@
+alter table products drop column marketplace;
+alter table categories drop column marketplace;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE categories ADD COLUMN `marketplace` VARCHAR(16) NOT NULL DEFAULT 'wb' AFTER `type`;
+
+ALTER TABLE products ADD COLUMN `marketplace` VARCHAR(16) NOT NULL DEFAULT 'wb' AFTER `id`;
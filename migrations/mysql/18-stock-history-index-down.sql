@@ -0,0 +1,3 @@
+alter table products_sizes_stock_history
+  add index index_product_size_region_created_at (product_id, size_id, region_id, created_at),
+  drop index index_product_size_region_id;
\ No newline at end of file
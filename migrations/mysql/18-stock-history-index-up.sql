@@ -0,0 +1,3 @@
+ALTER TABLE products_sizes_stock_history
+  ADD INDEX `index_product_size_region_id` (product_id, size_id, region_id, id),
+  DROP INDEX `index_product_size_region_created_at`;
@@ -0,0 +1,7 @@
+set foreign_key_checks = 0;
+
+alter table products modify column id bigint unsigned not null;
+
+set foreign_key_checks = 1;
+
+alter table products drop index uk_products_marketplace_external_id, drop column external_id;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- products were keyed by the id of the marketplace, ozon SKUs could land on a wildberries article with the same id.
+-- The id becomes a key of its own, the marketplace id moves to external_id and is only unique within the marketplace.
+
+-- the referencing tables keep their foreign keys, the type of the column does not change
+SET FOREIGN_KEY_CHECKS = 0;
+
+ALTER TABLE products MODIFY COLUMN `id` BIGINT UNSIGNED AUTO_INCREMENT NOT NULL;
+
+SET FOREIGN_KEY_CHECKS = 1;
+
+ALTER TABLE products ADD COLUMN `external_id` BIGINT UNSIGNED NULL AFTER `marketplace`;
+
+UPDATE products SET external_id = id;
+
+ALTER TABLE products
+  MODIFY COLUMN `external_id` BIGINT UNSIGNED NOT NULL,
+  ADD UNIQUE KEY `uk_products_marketplace_external_id` (`marketplace`, `external_id`);
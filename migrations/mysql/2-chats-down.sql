@@ -0,0 +1 @@
+drop table chats;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS chats (
+  `chat_id` BIGINT SIGNED NOT NULL PRIMARY KEY,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  `updated_at` DATETIME NULL
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
+
+insert ignore into
+  chats (chat_id)
+select distinct
+  chat_id
+from
+  tracking_settings;
@@ -0,0 +1 @@
+alter table products_sizes drop column price_changed_at;
\ No newline at end of file
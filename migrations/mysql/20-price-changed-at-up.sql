@@ -0,0 +1 @@
+ALTER TABLE products_sizes ADD COLUMN `price_changed_at` DATETIME NULL AFTER `return_fee`;
@@ -0,0 +1,3 @@
+alter table products add column missed_passes int unsigned not null default 0 after last_seen_at;
+update products as p set p.missed_passes = (select coalesce(min(cp.missed_passes), 0) from categories_products as cp where cp.product_id = p.id);
+alter table categories_products drop column missed_passes, drop column last_seen_at;
\ No newline at end of file
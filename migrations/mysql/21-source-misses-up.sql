@@ -0,0 +1,8 @@
+ALTER TABLE categories_products
+  ADD COLUMN `last_seen_at` DATETIME NULL,
+  ADD COLUMN `missed_passes` INT UNSIGNED NOT NULL DEFAULT 0;
+
+UPDATE categories_products AS cp JOIN products AS p ON p.id = cp.product_id
+SET cp.last_seen_at = p.last_seen_at, cp.missed_passes = p.missed_passes;
+
+ALTER TABLE products DROP COLUMN `missed_passes`;
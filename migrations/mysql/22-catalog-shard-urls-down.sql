@@ -0,0 +1,3 @@
+-- irreversible: categories added since are named shard_catid and siblings share the shard, so neither the shard-as-name
+-- nor the %s placeholder can be restored for them and the names don't fit varchar(20), restore a backup instead
+signal sqlstate '45000' set message_text = 'migration 22-catalog-shard-urls is irreversible';
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE categories MODIFY COLUMN `name` VARCHAR(64) NOT NULL;
+
+UPDATE categories SET request_url = REPLACE(request_url, '/catalog/%s/', CONCAT('/catalog/', name, '/'))
+WHERE type = 0 AND marketplace = 'wb';
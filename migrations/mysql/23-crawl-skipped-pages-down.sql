@@ -0,0 +1 @@
+alter table categories drop column crawl_skipped_pages;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE categories ADD COLUMN `crawl_skipped_pages` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `crawl_passes`;
@@ -0,0 +1,5 @@
+alter table products_sizes
+  add column previous_price bigint not null default 0 after first_price,
+  add column previous_product_price bigint not null default 0 after product_price;
+update products_sizes set previous_price = current_price, previous_product_price = product_price;
+drop table if exists categories_products_prices;
\ No newline at end of file
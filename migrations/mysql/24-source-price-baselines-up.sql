@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS categories_products_prices (
+  `category_id` BIGINT UNSIGNED NOT NULL,
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `size_id` BIGINT UNSIGNED NOT NULL,
+  `region_id` BIGINT UNSIGNED NOT NULL,
+  `price` BIGINT NOT NULL,
+  `product_price` BIGINT NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  `updated_at` DATETIME NULL,
+  PRIMARY KEY (`category_id`, `product_id`, `size_id`, `region_id`),
+  INDEX `index_product_id` (product_id),
+  FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE,
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (size_id) REFERENCES sizes(id) ON DELETE CASCADE,
+  FOREIGN KEY (region_id) REFERENCES regions(id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
+
+INSERT IGNORE INTO categories_products_prices (category_id, product_id, size_id, region_id, price, product_price)
+SELECT ts.category_id, ps.product_id, ps.size_id, ps.region_id, ps.previous_price, ps.previous_product_price
+FROM
+  tracking_settings AS ts
+  JOIN chats AS ch ON ch.chat_id = ts.chat_id
+  JOIN categories_products AS cp ON cp.category_id = ts.category_id
+  JOIN products_sizes AS ps ON ps.product_id = cp.product_id AND ps.size_id = ts.size_id AND ps.region_id = ch.region_id
+WHERE ts.type = 0;
+
+ALTER TABLE products_sizes
+  DROP COLUMN `previous_price`,
+  DROP COLUMN `previous_product_price`;
@@ -0,0 +1,2 @@
+drop table stock_subscriptions;
+alter table products_sizes drop column is_available;
\ No newline at end of file
@@ -0,0 +1,13 @@
+ALTER TABLE products_sizes ADD COLUMN `is_available` TINYINT(1) NOT NULL DEFAULT 1 AFTER `current_price_int`;
+
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+  `chat_id` BIGINT SIGNED NOT NULL,
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `size_id` BIGINT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  INDEX `index_product_id` (product_id),
+  FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE,
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (size_id) REFERENCES sizes(id) ON DELETE CASCADE,
+  UNIQUE KEY `uk_stock_subscriptions_chat_product_size` (`chat_id`, `product_id`, `size_id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
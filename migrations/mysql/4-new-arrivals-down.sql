@@ -0,0 +1,6 @@
+delete from tracking_settings where type <> 0;
+alter table tracking_settings
+  drop index uk_tracking_chat_size_category_type,
+  add unique key uk_tracking_chat_product_size (chat_id, size_id, category_id),
+  drop column max_price,
+  drop column type;
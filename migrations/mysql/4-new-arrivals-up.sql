@@ -0,0 +1,5 @@
+ALTER TABLE tracking_settings
+  ADD COLUMN `type` TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER `category_id`,
+  ADD COLUMN `max_price` DECIMAL(10, 2) NOT NULL DEFAULT 0.00 AFTER `diff_value`,
+  DROP INDEX `uk_tracking_chat_product_size`,
+  ADD UNIQUE KEY `uk_tracking_chat_size_category_type` (`chat_id`, `size_id`, `category_id`, `type`);
@@ -0,0 +1 @@
+alter table categories drop column crawled_at;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE categories ADD COLUMN `crawled_at` DATETIME NULL AFTER `product_url`;
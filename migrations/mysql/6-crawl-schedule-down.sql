@@ -0,0 +1 @@
+alter table categories drop index index_next_crawl_at, drop column throttle_count, drop column next_crawl_at;
\ No newline at end of file
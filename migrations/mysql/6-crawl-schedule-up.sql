@@ -0,0 +1,4 @@
+ALTER TABLE categories
+  ADD COLUMN `next_crawl_at` DATETIME NULL AFTER `crawled_at`,
+  ADD COLUMN `throttle_count` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `next_crawl_at`,
+  ADD INDEX `index_next_crawl_at` (next_crawl_at);
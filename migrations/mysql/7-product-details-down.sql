@@ -0,0 +1 @@
+drop table product_details;
\ No newline at end of file
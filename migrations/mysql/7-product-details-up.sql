@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS product_details (
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `description` TEXT NOT NULL,
+  `composition` VARCHAR(512) NOT NULL DEFAULT '',
+  `seller_id` BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  `seller_name` VARCHAR(255) NOT NULL DEFAULT '',
+  `images_count` SMALLINT UNSIGNED NOT NULL DEFAULT 0,
+  `size_chart` JSON NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  `updated_at` DATETIME NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (`product_id`),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
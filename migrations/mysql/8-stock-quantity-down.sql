@@ -0,0 +1,4 @@
+drop table low_stock_logs;
+drop table products_sizes_stock_history;
+alter table tracking_settings drop column stock_threshold;
+alter table products_sizes drop column quantity;
\ No newline at end of file
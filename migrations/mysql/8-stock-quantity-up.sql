@@ -0,0 +1,27 @@
+ALTER TABLE products_sizes
+  ADD COLUMN `quantity` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `is_available`;
+
+ALTER TABLE tracking_settings ADD COLUMN `stock_threshold` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `max_price`;
+
+CREATE TABLE IF NOT EXISTS products_sizes_stock_history (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `size_id` BIGINT UNSIGNED NOT NULL,
+  `quantity` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (`id`),
+  INDEX `index_product_size_created_at` (product_id, size_id, created_at),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (size_id) REFERENCES sizes(id) ON DELETE CASCADE
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
+
+CREATE TABLE IF NOT EXISTS low_stock_logs (
+  `chat_id` BIGINT SIGNED NOT NULL,
+  `product_id` BIGINT UNSIGNED NOT NULL,
+  `size_id` BIGINT UNSIGNED NOT NULL,
+  `quantity` INT UNSIGNED NOT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT NOW(),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (size_id) REFERENCES sizes(id) ON DELETE CASCADE,
+  UNIQUE KEY `uk_low_stock_logs_chat_product_size` (`chat_id`, `product_id`, `size_id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci ROW_FORMAT = COMPRESSED KEY_BLOCK_SIZE = 8;
@@ -0,0 +1,2 @@
+alter table tracking_settings drop column price_type;
+alter table products_sizes drop column basic_price, drop column product_price, drop column previous_product_price, drop column logistics_price, drop column return_fee;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE products_sizes
+  ADD COLUMN `basic_price` DECIMAL(10, 2) NOT NULL DEFAULT 0.00 AFTER `current_price_int`,
+  ADD COLUMN `product_price` DECIMAL(10, 2) NOT NULL DEFAULT 0.00 AFTER `basic_price`,
+  ADD COLUMN `previous_product_price` DECIMAL(10, 2) NOT NULL DEFAULT 0.00 AFTER `product_price`,
+  ADD COLUMN `logistics_price` DECIMAL(10, 2) NOT NULL DEFAULT 0.00 AFTER `previous_product_price`,
+  ADD COLUMN `return_fee` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `logistics_price`;
+
+UPDATE products_sizes SET product_price = current_price, previous_product_price = previous_price;
+
+ALTER TABLE tracking_settings ADD COLUMN `price_type` TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER `diff_value`;